@@ -0,0 +1,196 @@
+package archive
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	summariesBucket = []byte("summaries")
+	roomsBucket     = []byte("rooms")
+
+	ErrNotFound = errors.New("summary not found")
+)
+
+// Entry is one generated summary together with what it covered.
+type Entry struct {
+	ID           uint64    `json:"id"`
+	Room         string    `json:"room"`
+	CreatedAt    time.Time `json:"created_at"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	Participants []string  `json:"participants"`
+	MessageCount int       `json:"message_count"`
+	Model        string    `json:"model"`
+	MessageIDs   []string  `json:"message_ids"`
+	Content      string    `json:"content"`
+}
+
+// Archive stores summaries in a bbolt file. The file is opened per call so
+// the CLI can query it while the bot is running.
+type Archive struct {
+	path string
+}
+
+const openTimeout = 5 * time.Second
+
+func Open(path string) (*Archive, error) {
+	a := &Archive{path: path}
+	err := a.update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(summariesBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(roomsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive %s: %w", path, err)
+	}
+	return a, nil
+}
+
+func (a *Archive) update(fn func(tx *bolt.Tx) error) error {
+	db, err := bolt.Open(a.path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+func (a *Archive) view(fn func(tx *bolt.Tx) error) error {
+	db, err := bolt.Open(a.path, 0o600, &bolt.Options{Timeout: openTimeout, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+func itob(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
+
+// Save assigns e an ID and stores it.
+func (a *Archive) Save(e *Entry) error {
+	return a.update(func(tx *bolt.Tx) error {
+		summaries := tx.Bucket(summariesBucket)
+		id, err := summaries.NextSequence()
+		if err != nil {
+			return err
+		}
+		e.ID = id
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err := summaries.Put(itob(id), data); err != nil {
+			return err
+		}
+
+		room, err := tx.Bucket(roomsBucket).CreateBucketIfNotExists([]byte(e.Room))
+		if err != nil {
+			return err
+		}
+		return room.Put(itob(id), nil)
+	})
+}
+
+func (a *Archive) Get(id uint64) (Entry, error) {
+	var e Entry
+	err := a.view(func(tx *bolt.Tx) error {
+		data := tx.Bucket(summariesBucket).Get(itob(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &e)
+	})
+	return e, err
+}
+
+// Rooms returns all rooms with at least one archived summary.
+func (a *Archive) Rooms() ([]string, error) {
+	var rooms []string
+	err := a.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(roomsBucket).ForEachBucket(func(k []byte) error {
+			rooms = append(rooms, string(k))
+			return nil
+		})
+	})
+	return rooms, err
+}
+
+// List returns summaries newest first, optionally limited to one room.
+// A limit <= 0 returns everything.
+func (a *Archive) List(room string, limit int) ([]Entry, error) {
+	return a.find(room, limit, func(Entry) bool { return true })
+}
+
+// Search returns summaries whose content, room or participants contain
+// every whitespace-separated term of query, ignoring case.
+func (a *Archive) Search(room, query string, limit int) ([]Entry, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	return a.find(room, limit, func(e Entry) bool {
+		haystack := strings.ToLower(e.Room + "\n" + strings.Join(e.Participants, " ") + "\n" + e.Content)
+		for _, term := range terms {
+			if !strings.Contains(haystack, term) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Between returns the summaries of every room created in [since, until),
+// oldest first.
+func (a *Archive) Between(since, until time.Time) ([]Entry, error) {
+	entries, err := a.find("", 0, func(e Entry) bool {
+		return !e.CreatedAt.Before(since) && e.CreatedAt.Before(until)
+	})
+	slices.Reverse(entries)
+	return entries, err
+}
+
+func (a *Archive) find(room string, limit int, match func(Entry) bool) ([]Entry, error) {
+	var entries []Entry
+	err := a.view(func(tx *bolt.Tx) error {
+		summaries := tx.Bucket(summariesBucket)
+
+		var c *bolt.Cursor
+		if room == "" {
+			c = summaries.Cursor()
+		} else {
+			roomBucket := tx.Bucket(roomsBucket).Bucket([]byte(room))
+			if roomBucket == nil {
+				return nil
+			}
+			c = roomBucket.Cursor()
+		}
+
+		for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+			var e Entry
+			if err := json.Unmarshal(summaries.Get(k), &e); err != nil {
+				return fmt.Errorf("corrupt summary %d: %w", binary.BigEndian.Uint64(k), err)
+			}
+			if !match(e) {
+				continue
+			}
+			entries = append(entries, e)
+			if limit > 0 && len(entries) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+	return entries, err
+}
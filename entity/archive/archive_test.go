@@ -0,0 +1,90 @@
+package archive
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+
+func openTestArchive(t *testing.T) *Archive {
+	t.Helper()
+	a, err := Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func entryIDs(entries []Entry) []uint64 {
+	ids := make([]uint64, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestArchiveQueries(t *testing.T) {
+	a := openTestArchive(t)
+	for i, e := range []Entry{
+		{Room: "周会", Participants: []string{"张三"}, Content: "决定周五发布"},
+		{Room: "产品群", Participants: []string{"李四"}, Content: "预算讨论"},
+		{Room: "周会", Participants: []string{"王五"}, Content: "发布延期到下周"},
+	} {
+		e.CreatedAt = testStart.Add(time.Duration(i) * time.Hour)
+		if err := a.Save(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.ID != uint64(i+1) {
+			t.Fatalf("saved entry %d got ID %d", i, e.ID)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query func() ([]Entry, error)
+		want  []uint64
+	}{
+		{name: "list all newest first", query: func() ([]Entry, error) { return a.List("", 0) }, want: []uint64{3, 2, 1}},
+		{name: "list room", query: func() ([]Entry, error) { return a.List("周会", 0) }, want: []uint64{3, 1}},
+		{name: "list limit", query: func() ([]Entry, error) { return a.List("", 2) }, want: []uint64{3, 2}},
+		{name: "list unknown room", query: func() ([]Entry, error) { return a.List("无", 0) }, want: []uint64{}},
+		{name: "search all terms", query: func() ([]Entry, error) { return a.Search("", "发布 延期", 0) }, want: []uint64{3}},
+		{name: "search participants", query: func() ([]Entry, error) { return a.Search("", "李四", 0) }, want: []uint64{2}},
+		{name: "search room", query: func() ([]Entry, error) { return a.Search("产品群", "发布", 0) }, want: []uint64{}},
+		{
+			name:  "between oldest first",
+			query: func() ([]Entry, error) { return a.Between(testStart, testStart.Add(2*time.Hour)) },
+			want:  []uint64{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := tt.query()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := entryIDs(entries); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	rooms, err := a.Rooms()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(rooms)
+	if !slices.Equal(rooms, []string{"产品群", "周会"}) {
+		t.Errorf("rooms %v", rooms)
+	}
+	if e, err := a.Get(2); err != nil || e.Content != "预算讨论" {
+		t.Errorf("Get(2) = %+v, %v", e, err)
+	}
+	if _, err := a.Get(9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(9) err = %v, want ErrNotFound", err)
+	}
+}
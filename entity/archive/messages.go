@@ -0,0 +1,97 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	bolt "go.etcd.io/bbolt"
+)
+
+// messagesBucket holds one sub-bucket per room of raw summarized messages,
+// keyed by send time and ID so time ranges are a cursor seek.
+var messagesBucket = []byte("messages")
+
+func messageKey(msg buffer.BufferedMessage) []byte {
+	key := make([]byte, 8, 8+len(msg.ID))
+	binary.BigEndian.PutUint64(key, uint64(msg.Timestamp.UnixNano()))
+	return append(key, msg.ID...)
+}
+
+func timeKey(t time.Time) []byte {
+	return itob(uint64(t.UnixNano()))
+}
+
+// SaveMessages stores the raw messages of a summary so on-demand queries
+// can reach past the buffer, and drops the room's messages sent before
+// retainSince unless it is zero.
+func (a *Archive) SaveMessages(room string, msgs []buffer.BufferedMessage, retainSince time.Time) error {
+	return a.update(func(tx *bolt.Tx) error {
+		messages, err := tx.CreateBucketIfNotExists(messagesBucket)
+		if err != nil {
+			return err
+		}
+		bucket, err := messages.CreateBucketIfNotExists([]byte(room))
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(messageKey(msg), data); err != nil {
+				return err
+			}
+		}
+
+		if retainSince.IsZero() {
+			return nil
+		}
+		c := bucket.Cursor()
+		end := timeKey(retainSince)
+		for k, _ := c.First(); k != nil && bytes.Compare(k[:8], end) < 0; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Messages returns the archived messages of room sent in [since, until),
+// oldest first. A zero until has no upper bound.
+func (a *Archive) Messages(room string, since, until time.Time) ([]buffer.BufferedMessage, error) {
+	var msgs []buffer.BufferedMessage
+	err := a.view(func(tx *bolt.Tx) error {
+		messages := tx.Bucket(messagesBucket)
+		if messages == nil {
+			return nil
+		}
+		bucket := messages.Bucket([]byte(room))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		k, v := c.First()
+		if !since.IsZero() {
+			k, v = c.Seek(timeKey(since))
+		}
+		for ; k != nil; k, v = c.Next() {
+			if !until.IsZero() && bytes.Compare(k[:8], timeKey(until)) >= 0 {
+				break
+			}
+			var msg buffer.BufferedMessage
+			if err := json.Unmarshal(v, &msg); err != nil {
+				return err
+			}
+			msgs = append(msgs, msg)
+		}
+		return nil
+	})
+	return msgs, err
+}
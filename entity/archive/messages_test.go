@@ -0,0 +1,103 @@
+package archive
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+)
+
+func hourlyMessages(room string, from, to int) []buffer.BufferedMessage {
+	var msgs []buffer.BufferedMessage
+	for i := from; i <= to; i++ {
+		msgs = append(msgs, buffer.BufferedMessage{
+			ID:        fmt.Sprintf("m%d", i),
+			Timestamp: testStart.Add(time.Duration(i) * time.Hour),
+			Sender:    "alice",
+			Content:   fmt.Sprintf("message %d", i),
+			RoomTopic: room,
+		})
+	}
+	return msgs
+}
+
+func messageIDs(msgs []buffer.BufferedMessage) []string {
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestMessagesRange(t *testing.T) {
+	a := openTestArchive(t)
+	if err := a.SaveMessages("周会", hourlyMessages("周会", 0, 4), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SaveMessages("产品群", hourlyMessages("产品群", 9, 9), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		room         string
+		since, until time.Time
+		want         []string
+	}{
+		{name: "everything", room: "周会", want: []string{"m0", "m1", "m2", "m3", "m4"}},
+		{name: "since", room: "周会", since: testStart.Add(3 * time.Hour), want: []string{"m3", "m4"}},
+		{name: "until is exclusive", room: "周会", until: testStart.Add(2 * time.Hour), want: []string{"m0", "m1"}},
+		{
+			name:  "window",
+			room:  "周会",
+			since: testStart.Add(time.Hour), until: testStart.Add(90 * time.Minute),
+			want: []string{"m1"},
+		},
+		{name: "other room", room: "产品群", want: []string{"m9"}},
+		{name: "unknown room", room: "无", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := a.Messages(tt.room, tt.since, tt.until)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := messageIDs(msgs); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveMessagesRetention(t *testing.T) {
+	a := openTestArchive(t)
+	if err := a.SaveMessages("周会", hourlyMessages("周会", 0, 2), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SaveMessages("产品群", hourlyMessages("产品群", 0, 1), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next save drops the room's messages sent before the cutoff,
+	// including ones from earlier summaries, and leaves other rooms alone.
+	if err := a.SaveMessages("周会", hourlyMessages("周会", 3, 4), testStart.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	kept, err := a.Messages("周会", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := messageIDs(kept); !slices.Equal(got, []string{"m2", "m3", "m4"}) {
+		t.Errorf("kept %v, want [m2 m3 m4]", got)
+	}
+	other, err := a.Messages("产品群", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other) != 2 {
+		t.Errorf("other room kept %d messages, want 2", len(other))
+	}
+}
@@ -1,21 +1,27 @@
 package buffer
 
 import (
-	"fmt"
-	"log"
+	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/alphadose/haxmap"
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
 	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
 )
 
 type BufferedMessage struct {
-	ID        string
-	Timestamp time.Time
-	Sender    string
-	Content   string
-	RoomTopic string
+	ID        string     `json:"id"`
+	Timestamp time.Time  `json:"timestamp"`
+	Sender    string     `json:"sender"`
+	Content   string     `json:"content"`
+	RoomTopic string     `json:"room"`
+	Kind      chat.Kind  `json:"kind,omitempty"`
+	Meta      *chat.Meta `json:"meta,omitempty"`
 }
 
 type roomData struct {
@@ -26,22 +32,102 @@ type roomData struct {
 	capacity        int
 	lastSummaryTime time.Time
 	messageIDs      map[string]struct{}
+	rolling         *RollingState
+	logSize         int
+	// added counts every message ever accepted; it is the sequence number
+	// of the newest message and is never reset.
+	added uint64
+}
+
+// RollingState is the cumulative summary of a room carried across clears.
+type RollingState struct {
+	Summary      string    `json:"summary"`
+	Since        time.Time `json:"since"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	MessageCount int       `json:"message_count"`
+	Participants []string  `json:"participants"`
 }
 
 type MessageBuffer struct {
-	rooms *haxmap.Map[string, *roomData]
+	rooms  *haxmap.Map[string, *roomData]
+	store  Store
+	logger *slog.Logger
 }
 
 func New() *MessageBuffer {
-	return &MessageBuffer{
-		rooms: haxmap.New[string, *roomData](),
+	logger := logging.For(logging.Buffer)
+
+	dir := config.Current().BufferPersistDir
+	if dir == "" {
+		return &MessageBuffer{
+			rooms:  haxmap.New[string, *roomData](),
+			logger: logger,
+		}
+	}
+
+	store, err := NewFileStore(dir)
+	if err != nil {
+		logging.Fatal(logger, "failed to open buffer store", "dir", dir, "err", err)
+	}
+	b, err := NewWithStore(store)
+	if err != nil {
+		logging.Fatal(logger, "failed to restore buffer", "dir", dir, "err", err)
+	}
+	return b
+}
+
+// NewWithStore creates a buffer backed by store and replays its records.
+func NewWithStore(store Store) (*MessageBuffer, error) {
+	b := &MessageBuffer{
+		rooms:  haxmap.New[string, *roomData](),
+		store:  store,
+		logger: logging.For(logging.Buffer),
+	}
+
+	records, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rec := range records {
+		room := b.getOrCreateRoom(rec.RoomTopic)
+		switch rec.Op {
+		case OpAdd:
+			if rec.Message != nil {
+				room.add(*rec.Message)
+			}
+		case OpUpdate:
+			if rec.Message != nil {
+				room.update(*rec.Message)
+			}
+		case OpClear:
+			room.reset(rec.Time)
+		case OpRolling:
+			room.rolling = rec.Rolling
+		}
+		room.logSize++
+	}
+
+	b.rooms.ForEach(func(topic string, room *roomData) bool {
+		b.logger.Info("restored messages", "room", topic, "count", room.count)
+		return true
+	})
+	return b, nil
+}
+
+func (b *MessageBuffer) Close() {
+	if b.store == nil {
+		return
+	}
+	if err := b.store.Close(); err != nil {
+		b.logger.Error("failed to close buffer store", "err", err)
 	}
 }
 
 func (b *MessageBuffer) getOrCreateRoom(roomTopic string) *roomData {
 	room, ok := b.rooms.Get(roomTopic)
 	if !ok {
-		cap := config.AppConfig.MaxBufferSize
+		cap := config.Current().ForRoom(roomTopic).MaxBufferSize
 		room = &roomData{
 			messages:   make([]BufferedMessage, cap),
 			capacity:   cap,
@@ -57,26 +143,163 @@ func (b *MessageBuffer) Add(msg BufferedMessage) {
 	room.mu.Lock()
 	defer room.mu.Unlock()
 
-	if _, ok := room.messageIDs[msg.ID]; ok {
-		log.Printf("[Buffer] Duplicate message ID '%s' detected in room '%s', skipping", msg.ID, msg.RoomTopic)
+	if !room.add(msg) {
+		metrics.DuplicatesSkipped.WithLabelValues(msg.RoomTopic).Inc()
+		b.logger.Debug("duplicate message skipped", "room", msg.RoomTopic, "msg_id", msg.ID)
 		return
 	}
+	metrics.MessagesBuffered.WithLabelValues(msg.RoomTopic).Inc()
+
+	if b.store != nil {
+		if err := b.store.Append(Record{Op: OpAdd, RoomTopic: msg.RoomTopic, Message: &msg}); err != nil {
+			b.logger.Error("failed to persist message", "room", msg.RoomTopic, "msg_id", msg.ID, "err", err)
+		}
+		room.logSize++
+		// Evicted ring entries stay in the log until compaction.
+		if room.logSize > 2*room.capacity {
+			b.compactLocked(msg.RoomTopic, room)
+		}
+	}
+
+	b.logger.Debug("message added", "room", msg.RoomTopic, "msg_id", msg.ID, "count", room.count)
+}
+
+func (r *roomData) add(msg BufferedMessage) bool {
+	if _, ok := r.messageIDs[msg.ID]; ok {
+		return false
+	}
+
+	firstMsg := r.writeIndex
+	if r.count == r.capacity {
+		firstMsgID := r.messages[firstMsg].ID
+		delete(r.messageIDs, firstMsgID)
+	}
+
+	r.messages[r.writeIndex] = msg
+	r.messageIDs[msg.ID] = struct{}{}
+	r.writeIndex = (r.writeIndex + 1) % r.capacity
+
+	if r.count < r.capacity {
+		r.count++
+	}
+	r.added++
+	return true
+}
+
+// Update replaces the buffered message with the ID of msg, such as when a
+// download or transcript completes after the placeholder was buffered. It
+// returns false if the message is no longer buffered.
+func (b *MessageBuffer) Update(msg BufferedMessage) bool {
+	room, ok := b.rooms.Get(msg.RoomTopic)
+	if !ok {
+		return false
+	}
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	if !room.update(msg) {
+		return false
+	}
+	if b.store != nil {
+		if err := b.store.Append(Record{Op: OpUpdate, RoomTopic: msg.RoomTopic, Message: &msg}); err != nil {
+			b.logger.Error("failed to persist message update", "room", msg.RoomTopic, "msg_id", msg.ID, "err", err)
+		}
+		room.logSize++
+	}
+	b.logger.Debug("message updated", "room", msg.RoomTopic, "msg_id", msg.ID)
+	return true
+}
 
-	firstMsg := room.writeIndex
-	if room.count == room.capacity {
-		firstMsgID := room.messages[firstMsg].ID
-		delete(room.messageIDs, firstMsgID)
+func (r *roomData) update(msg BufferedMessage) bool {
+	if _, ok := r.messageIDs[msg.ID]; !ok {
+		return false
+	}
+	start := 0
+	if r.count == r.capacity {
+		start = r.writeIndex
 	}
+	for i := range r.count {
+		if j := (start + i) % r.capacity; r.messages[j].ID == msg.ID {
+			r.messages[j] = msg
+			return true
+		}
+	}
+	return false
+}
+
+func (r *roomData) reset(at time.Time) {
+	r.writeIndex = 0
+	r.count = 0
+	r.messageIDs = make(map[string]struct{})
+	r.lastSummaryTime = at
+}
 
-	room.messages[room.writeIndex] = msg
-	room.messageIDs[msg.ID] = struct{}{}
-	room.writeIndex = (room.writeIndex + 1) % room.capacity
+// dropOldest removes the n oldest messages and their IDs, keeping the rest
+// in order.
+func (r *roomData) dropOldest(n int, at time.Time) {
+	kept := r.ordered()[n:]
+	r.reset(at)
+	for i, msg := range kept {
+		r.messages[i] = msg
+		r.messageIDs[msg.ID] = struct{}{}
+	}
+	r.count = len(kept)
+	r.writeIndex = r.count % r.capacity
+}
 
-	if room.count < room.capacity {
-		room.count++
+// resize changes the ring's capacity, dropping the oldest messages that no
+// longer fit, and returns how many were dropped.
+func (r *roomData) resize(capacity int) int {
+	msgs := r.ordered()
+	dropped := max(len(msgs)-capacity, 0)
+	for _, msg := range msgs[:dropped] {
+		delete(r.messageIDs, msg.ID)
 	}
+	msgs = msgs[dropped:]
+
+	r.messages = make([]BufferedMessage, capacity)
+	copy(r.messages, msgs)
+	r.capacity = capacity
+	r.count = len(msgs)
+	r.writeIndex = r.count % capacity
+	return dropped
+}
 
-	log.Printf("[Buffer] Message added to room '%s'. Total: %d (ring buffer)", msg.RoomTopic, room.count)
+// ordered returns the buffered messages oldest first.
+func (r *roomData) ordered() []BufferedMessage {
+	if r.count == 0 {
+		return nil
+	}
+
+	startIndex := 0
+	if r.count == r.capacity {
+		startIndex = r.writeIndex
+	}
+
+	msgs := make([]BufferedMessage, r.count)
+	for i := 0; i < r.count; i++ {
+		msgs[i] = r.messages[(startIndex+i)%r.capacity]
+	}
+	return msgs
+}
+
+func (b *MessageBuffer) compactLocked(roomTopic string, room *roomData) {
+	records := make([]Record, 0, room.count+1)
+	if !room.lastSummaryTime.IsZero() {
+		records = append(records, Record{Op: OpClear, RoomTopic: roomTopic, Time: room.lastSummaryTime})
+	}
+	if room.rolling != nil {
+		records = append(records, Record{Op: OpRolling, RoomTopic: roomTopic, Rolling: room.rolling})
+	}
+	for _, msg := range room.ordered() {
+		records = append(records, Record{Op: OpAdd, RoomTopic: roomTopic, Message: &msg})
+	}
+
+	if err := b.store.Compact(roomTopic, records); err != nil {
+		b.logger.Error("failed to compact store", "room", roomTopic, "err", err)
+		return
+	}
+	room.logSize = len(records)
 }
 
 func (b *MessageBuffer) GetRoomTopics() []string {
@@ -88,6 +311,59 @@ func (b *MessageBuffer) GetRoomTopics() []string {
 	return topics
 }
 
+// RoomStats describes the buffered state of one room.
+type RoomStats struct {
+	Room            string    `json:"room"`
+	Count           int       `json:"count"`
+	Capacity        int       `json:"capacity"`
+	LastSummaryTime time.Time `json:"last_summary_time"`
+}
+
+func (b *MessageBuffer) Stats() []RoomStats {
+	stats := make([]RoomStats, 0)
+	b.rooms.ForEach(func(topic string, room *roomData) bool {
+		room.mu.Lock()
+		stats = append(stats, RoomStats{
+			Room:            topic,
+			Count:           room.count,
+			Capacity:        room.capacity,
+			LastSummaryTime: room.lastSummaryTime,
+		})
+		room.mu.Unlock()
+		return true
+	})
+	return stats
+}
+
+// Resize applies the current MAX_BUFFER_SIZE and per-room buffer_size to
+// every room. A room that shrinks below its message count loses its oldest
+// messages.
+func (b *MessageBuffer) Resize() {
+	cfg := config.Current()
+	b.rooms.ForEach(func(topic string, room *roomData) bool {
+		capacity := cfg.ForRoom(topic).MaxBufferSize
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		if room.capacity == capacity {
+			return true
+		}
+
+		previous := room.capacity
+		dropped := room.resize(capacity)
+		b.logger.Info("buffer resized", "room", topic, "from", previous, "to", capacity, "dropped", dropped)
+		if dropped > 0 && b.store != nil {
+			b.compactLocked(topic, room)
+		}
+		return true
+	})
+}
+
+// HasRoom reports whether the buffer has seen any message for roomTopic.
+func (b *MessageBuffer) HasRoom(roomTopic string) bool {
+	_, ok := b.rooms.Get(roomTopic)
+	return ok
+}
+
 func (b *MessageBuffer) Clear(roomTopic string) {
 	room, ok := b.rooms.Get(roomTopic)
 	if !ok {
@@ -97,11 +373,73 @@ func (b *MessageBuffer) Clear(roomTopic string) {
 	room.mu.Lock()
 	defer room.mu.Unlock()
 
-	log.Printf("[Buffer] Clear %d messages from room '%s'", room.count, roomTopic)
-	room.writeIndex = 0
-	room.count = 0
-	room.messageIDs = make(map[string]struct{})
-	room.lastSummaryTime = time.Now()
+	b.logger.Info("clearing room", "room", roomTopic, "count", room.count)
+	room.reset(time.Now())
+
+	if b.store != nil {
+		b.compactLocked(roomTopic, room)
+	}
+}
+
+// ClearUpTo removes the messages covered by the snapshot that returned
+// cursor. Messages added after the snapshot stay buffered with their IDs.
+func (b *MessageBuffer) ClearUpTo(cursor Cursor) {
+	room, ok := b.rooms.Get(cursor.Room)
+	if !ok {
+		return
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	// The ring holds the newest room.count messages, so everything older
+	// than the last room.added-cursor.Seq of them was in the snapshot.
+	kept := min(room.count, int(room.added-cursor.Seq))
+	removed := room.count - kept
+	if removed == 0 {
+		// Nothing of the snapshot is left, so the interval trigger and the
+		// log stay as they are.
+		return
+	}
+
+	b.logger.Info("clearing summarized messages", "room", cursor.Room, "count", removed, "kept", kept)
+	room.dropOldest(removed, time.Now())
+
+	if b.store != nil {
+		b.compactLocked(cursor.Room, room)
+	}
+}
+
+// RollingSummary returns the room's cumulative summary state, or nil if none.
+func (b *MessageBuffer) RollingSummary(roomTopic string) *RollingState {
+	room, ok := b.rooms.Get(roomTopic)
+	if !ok {
+		return nil
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	if room.rolling == nil {
+		return nil
+	}
+	state := *room.rolling
+	return &state
+}
+
+func (b *MessageBuffer) SetRollingSummary(roomTopic string, state RollingState) {
+	room := b.getOrCreateRoom(roomTopic)
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	room.rolling = &state
+
+	if b.store != nil {
+		if err := b.store.Append(Record{Op: OpRolling, RoomTopic: roomTopic, Rolling: &state}); err != nil {
+			b.logger.Error("failed to persist rolling summary", "room", roomTopic, "err", err)
+		}
+		room.logSize++
+	}
 }
 
 func (b *MessageBuffer) ShouldSummarize(roomTopic string, triggeredByKeyword bool) bool {
@@ -113,30 +451,36 @@ func (b *MessageBuffer) ShouldSummarize(roomTopic string, triggeredByKeyword boo
 	room.mu.Lock()
 	defer room.mu.Unlock()
 
-	if room.count < config.AppConfig.SummaryTrigger.MinMessagesForSummary {
-		log.Printf("[Buffer] Not enough messages in room '%s' for summary (%d/%d)",
-			roomTopic, room.count, config.AppConfig.SummaryTrigger.MinMessagesForSummary)
+	trigger := config.Current().ForRoom(roomTopic).SummaryTrigger
+
+	if room.count < trigger.MinMessagesForSummary {
+		b.logger.Debug("not enough messages for summary",
+			"room", roomTopic, "count", room.count, "min", trigger.MinMessagesForSummary)
 		return false
 	}
 
 	if triggeredByKeyword {
-		log.Printf("[Buffer] Summary triggered by keyword in room '%s'", roomTopic)
+		metrics.SummariesTriggered.WithLabelValues(roomTopic, "keyword").Inc()
+		b.logger.Info("summary triggered", "room", roomTopic, "trigger", "keyword")
 		return true
 	}
 
-	if config.AppConfig.SummaryTrigger.MessageCount > 0 &&
-		room.count >= config.AppConfig.SummaryTrigger.MessageCount {
-		log.Printf("[Buffer] Summary triggered by message count in room '%s' (%d/%d)",
-			roomTopic, room.count, config.AppConfig.SummaryTrigger.MessageCount)
+	if trigger.MessageCount > 0 &&
+		room.count >= trigger.MessageCount {
+		metrics.SummariesTriggered.WithLabelValues(roomTopic, "count").Inc()
+		b.logger.Info("summary triggered", "room", roomTopic, "trigger", "count",
+			"count", room.count, "threshold", trigger.MessageCount)
 		return true
 	}
 
-	if config.AppConfig.SummaryTrigger.IntervalMinutes > 0 {
+	// A schedule replaces the interval trigger; the scheduler calls ReadyForSummary.
+	if trigger.IntervalMinutes > 0 && trigger.Schedule == "" {
 		if !room.lastSummaryTime.IsZero() {
 			minutesSinceLast := time.Since(room.lastSummaryTime).Minutes()
-			if minutesSinceLast >= float64(config.AppConfig.SummaryTrigger.IntervalMinutes) {
-				log.Printf("[Buffer] Summary triggered by time interval in room '%s' (%.1f/%d minutes)",
-					roomTopic, minutesSinceLast, config.AppConfig.SummaryTrigger.IntervalMinutes)
+			if minutesSinceLast >= float64(trigger.IntervalMinutes) {
+				metrics.SummariesTriggered.WithLabelValues(roomTopic, "interval").Inc()
+				b.logger.Info("summary triggered", "room", roomTopic, "trigger", "interval",
+					"minutes_since_last", minutesSinceLast, "interval_minutes", trigger.IntervalMinutes)
 				return true
 			}
 		}
@@ -145,53 +489,157 @@ func (b *MessageBuffer) ShouldSummarize(roomTopic string, triggeredByKeyword boo
 	return false
 }
 
+// ImageRef points at a downloaded image and the FormattedMsg line it came from.
+type ImageRef struct {
+	Line int
+	Path string
+}
+
+// ReadyForSummary reports whether roomTopic holds at least the room's
+// minimum number of messages, the only condition of a scheduled summary.
+func (b *MessageBuffer) ReadyForSummary(roomTopic string) bool {
+	room, ok := b.rooms.Get(roomTopic)
+	if !ok {
+		return false
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	minCount := config.Current().ForRoom(roomTopic).SummaryTrigger.MinMessagesForSummary
+	return room.count > 0 && room.count >= minCount
+}
+
+// PendingSince returns when the room's unsummarized messages started to
+// accumulate: its last summary, or its oldest message if it has none.
+func (b *MessageBuffer) PendingSince(roomTopic string) time.Time {
+	room, ok := b.rooms.Get(roomTopic)
+	if !ok {
+		return time.Time{}
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if !room.lastSummaryTime.IsZero() || room.count == 0 {
+		return room.lastSummaryTime
+	}
+	return room.ordered()[0].Timestamp
+}
+
+// Cursor marks the newest message of a snapshot.
+type Cursor struct {
+	Room string
+	Seq  uint64
+}
+
 type Snapshot struct {
 	Count        int
 	FirstMsgTime *time.Time
 	LastMsgTime  *time.Time
 	Participants map[string]struct{}
 	FormattedMsg []string
+	MessageIDs   []string
+	// Messages are the raw messages behind FormattedMsg.
+	Messages []BufferedMessage
+	// Images lists the downloaded pictures in the snapshot, oldest first.
+	Images []ImageRef
+	// Cursor is passed to ClearUpTo once the snapshot has been summarized.
+	Cursor Cursor
 }
 
 func (b *MessageBuffer) GetSnapshot(roomTopic string) Snapshot {
 	room, ok := b.rooms.Get(roomTopic)
 	if !ok {
-		return Snapshot{
-			Count:        0,
-			Participants: make(map[string]struct{}),
-			FormattedMsg: nil,
-		}
+		return NewSnapshot(roomTopic, nil)
 	}
 
 	room.mu.Lock()
 	defer room.mu.Unlock()
 
-	snapshot := Snapshot{
-		Count:        room.count,
-		Participants: make(map[string]struct{}),
-	}
+	snapshot := NewSnapshot(roomTopic, room.ordered())
+	snapshot.Cursor.Seq = room.added
+	return snapshot
+}
 
-	if room.count > 0 {
-		startIndex := 0
-		if room.count == room.capacity {
-			startIndex = room.writeIndex
-		}
+// Filter selects the messages of an on-demand summary. Zero fields match
+// everything.
+type Filter struct {
+	// Since and Until bound the message time; Until is exclusive.
+	Since time.Time
+	Until time.Time
+	// Senders keeps messages from any of these senders, ignoring case.
+	Senders []string
+	// Topic keeps messages whose text contains it, ignoring case.
+	Topic string
+}
+
+// IsZero reports whether f matches every message.
+func (f Filter) IsZero() bool {
+	return f.Since.IsZero() && f.Until.IsZero() && len(f.Senders) == 0 && f.Topic == ""
+}
 
-		firstMsg := room.messages[startIndex]
-		lastMsg := room.messages[(startIndex+room.count-1)%room.capacity]
+// Match reports whether msg is selected by f.
+func (f Filter) Match(msg BufferedMessage) bool {
+	if !f.Since.IsZero() && msg.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !msg.Timestamp.Before(f.Until) {
+		return false
+	}
+	if len(f.Senders) > 0 && !slices.ContainsFunc(f.Senders, func(s string) bool {
+		return strings.EqualFold(s, msg.Sender)
+	}) {
+		return false
+	}
+	return f.Topic == "" || strings.Contains(strings.ToLower(msg.Text()), strings.ToLower(f.Topic))
+}
+
+// Messages returns the buffered messages of roomTopic selected by filter,
+// oldest first, without removing them.
+func (b *MessageBuffer) Messages(roomTopic string, filter Filter) []BufferedMessage {
+	room, ok := b.rooms.Get(roomTopic)
+	if !ok {
+		return nil
+	}
 
-		snapshot.FirstMsgTime = &firstMsg.Timestamp
-		snapshot.LastMsgTime = &lastMsg.Timestamp
+	room.mu.Lock()
+	defer room.mu.Unlock()
 
-		snapshot.FormattedMsg = make([]string, room.count)
-		for i := 0; i < room.count; i++ {
-			msgIndex := (startIndex + i) % room.capacity
-			msg := room.messages[msgIndex]
-			snapshot.Participants[msg.Sender] = struct{}{}
-			timeStr := msg.Timestamp.Format("15:04")
-			snapshot.FormattedMsg[i] = fmt.Sprintf("[%s] %s: %s", timeStr, msg.Sender, msg.Content)
+	var msgs []BufferedMessage
+	for _, msg := range room.ordered() {
+		if filter.Match(msg) {
+			msgs = append(msgs, msg)
 		}
 	}
+	return msgs
+}
 
+// NewSnapshot builds a snapshot of msgs, which must be oldest first. Its
+// cursor covers nothing, so passing it to ClearUpTo keeps the buffer.
+func NewSnapshot(roomTopic string, msgs []BufferedMessage) Snapshot {
+	snapshot := Snapshot{
+		Messages:     msgs,
+		Count:        len(msgs),
+		Participants: make(map[string]struct{}),
+		Cursor:       Cursor{Room: roomTopic},
+	}
+	if len(msgs) == 0 {
+		return snapshot
+	}
+
+	firstMsg := msgs[0]
+	lastMsg := msgs[len(msgs)-1]
+	snapshot.FirstMsgTime = &firstMsg.Timestamp
+	snapshot.LastMsgTime = &lastMsg.Timestamp
+
+	snapshot.FormattedMsg = make([]string, len(msgs))
+	snapshot.MessageIDs = make([]string, len(msgs))
+	for i, msg := range msgs {
+		snapshot.MessageIDs[i] = msg.ID
+		snapshot.Participants[msg.Sender] = struct{}{}
+		snapshot.FormattedMsg[i] = msg.Line()
+		if msg.Kind == chat.KindImage && msg.Meta != nil && msg.Meta.Path != "" {
+			snapshot.Images = append(snapshot.Images, ImageRef{Line: i, Path: msg.Meta.Path})
+		}
+	}
 	return snapshot
 }
@@ -0,0 +1,131 @@
+package buffer
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+)
+
+var testStart = time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+
+func setBufferSize(t *testing.T, size int) {
+	t.Helper()
+	old := config.Current()
+	config.Set(&config.Config{MaxBufferSize: size})
+	t.Cleanup(func() { config.Set(old) })
+}
+
+func testMessage(room string, i int) BufferedMessage {
+	return BufferedMessage{
+		ID:        fmt.Sprintf("m%d", i),
+		Timestamp: testStart.Add(time.Duration(i) * time.Minute),
+		Sender:    "alice",
+		Content:   fmt.Sprintf("message %d", i),
+		RoomTopic: room,
+	}
+}
+
+func addMessages(b *MessageBuffer, room string, from, to int) {
+	for i := from; i <= to; i++ {
+		b.Add(testMessage(room, i))
+	}
+}
+
+func messageIDs(msgs []BufferedMessage) []string {
+	ids := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestClearUpTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		// before messages are buffered when the snapshot is taken, after
+		// arrive while it is summarized.
+		before, after int
+		want          []string
+	}{
+		{name: "all summarized", capacity: 5, before: 3, want: []string{}},
+		{name: "newer messages kept", capacity: 5, before: 3, after: 2, want: []string{"m4", "m5"}},
+		{name: "ring wrapped after snapshot", capacity: 4, before: 3, after: 3, want: []string{"m4", "m5", "m6"}},
+		{name: "snapshot evicted", capacity: 3, before: 2, after: 5, want: []string{"m5", "m6", "m7"}},
+		{name: "full ring summarized", capacity: 3, before: 5, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setBufferSize(t, tt.capacity)
+			b := New()
+			addMessages(b, "room", 1, tt.before)
+			cursor := b.GetSnapshot("room").Cursor
+			addMessages(b, "room", tt.before+1, tt.before+tt.after)
+
+			b.ClearUpTo(cursor)
+
+			if got := messageIDs(b.Messages("room", Filter{})); !slices.Equal(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClearUpToNothingSummarized(t *testing.T) {
+	setBufferSize(t, 5)
+	dir := t.TempDir()
+	b := openFileBuffer(t, dir)
+	defer b.Close()
+	addMessages(b, "room", 1, 3)
+	before, err := os.Stat(walFile(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.ClearUpTo(NewSnapshot("room", nil).Cursor)
+
+	if got := len(b.Messages("room", Filter{})); got != 3 {
+		t.Errorf("kept %d messages, want 3", got)
+	}
+	if stats := b.Stats(); len(stats) != 1 || !stats[0].LastSummaryTime.IsZero() {
+		t.Errorf("stats %+v, want no summary time", stats)
+	}
+	after, err := os.Stat(walFile(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Error("the wal was rewritten")
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	msg := testMessage("room", 5)
+	msg.Sender, msg.Content = "Alice", "Release on Friday"
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{name: "zero", want: true},
+		{name: "since inclusive", filter: Filter{Since: msg.Timestamp}, want: true},
+		{name: "before since", filter: Filter{Since: msg.Timestamp.Add(time.Second)}},
+		{name: "until exclusive", filter: Filter{Until: msg.Timestamp}},
+		{name: "sender ignores case", filter: Filter{Senders: []string{"bob", "alice"}}, want: true},
+		{name: "other sender", filter: Filter{Senders: []string{"bob"}}},
+		{name: "topic ignores case", filter: Filter{Topic: "release"}, want: true},
+		{name: "other topic", filter: Filter{Topic: "budget"}},
+		{name: "all terms", filter: Filter{Since: testStart, Senders: []string{"alice"}, Topic: "friday"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(msg); got != tt.want {
+				t.Errorf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
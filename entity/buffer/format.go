@@ -0,0 +1,87 @@
+package buffer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
+)
+
+// Line renders msg as a "[HH:MM] sender: text" transcript line.
+func (m BufferedMessage) Line() string {
+	return fmt.Sprintf("[%s] %s: %s", m.Timestamp.Format("15:04"), m.Sender, m.Text())
+}
+
+// Text is the message content with non-text parts rendered as typed
+// placeholders, e.g. "[文件: design.pdf]", so the minutes can mention them.
+func (m BufferedMessage) Text() string {
+	meta := m.Meta
+	if meta == nil {
+		meta = &chat.Meta{}
+	}
+
+	switch m.Kind {
+	case chat.KindImage:
+		return join("[图片]", m.Content)
+	case chat.KindFile:
+		placeholder := "[文件: " + meta.FileName
+		if meta.FileSize > 0 {
+			placeholder += ", " + formatSize(meta.FileSize)
+		}
+		return join(placeholder+"]", m.Content)
+	case chat.KindLink:
+		placeholder := "[链接: " + meta.Title
+		if meta.URL != "" {
+			placeholder += " " + meta.URL
+		}
+		return join(placeholder+"]", m.Content)
+	case chat.KindVoice:
+		if meta.Transcribed {
+			return join(withSeconds("[语音转文字", meta.Seconds), m.Content)
+		}
+		return join(withSeconds("[语音", meta.Seconds), m.Content)
+	case chat.KindVideo:
+		return join(withSeconds("[视频", meta.Seconds), m.Content)
+	case chat.KindQuote:
+		if meta.Quote == nil {
+			return m.Content
+		}
+		return fmt.Sprintf("[回复 %s: %s] %s", meta.Quote.Sender, truncate(meta.Quote.Content, 50), m.Content)
+	default:
+		return m.Content
+	}
+}
+
+func join(placeholder, content string) string {
+	if content == "" {
+		return placeholder
+	}
+	return placeholder + " " + content
+}
+
+func withSeconds(prefix string, seconds int) string {
+	if seconds > 0 {
+		return fmt.Sprintf("%s %d秒]", prefix, seconds)
+	}
+	return prefix + "]"
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
+
+func truncate(s string, limit int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit]) + "…"
+}
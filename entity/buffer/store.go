@@ -0,0 +1,205 @@
+package buffer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/filename"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+)
+
+type RecordOp string
+
+const (
+	OpAdd     RecordOp = "add"
+	OpUpdate  RecordOp = "update"
+	OpClear   RecordOp = "clear"
+	OpRolling RecordOp = "rolling"
+)
+
+// Record is a single buffer mutation as written to a Store.
+type Record struct {
+	Op        RecordOp         `json:"op"`
+	RoomTopic string           `json:"room"`
+	Message   *BufferedMessage `json:"msg,omitempty"`
+	Rolling   *RollingState    `json:"rolling,omitempty"`
+	Time      time.Time        `json:"time,omitzero"`
+}
+
+// Store persists buffer mutations so that a MessageBuffer can be rebuilt
+// after a restart. Records of one room must be returned by Load in the
+// order they were appended.
+type Store interface {
+	Load() ([]Record, error)
+	Append(rec Record) error
+	// Compact replaces everything stored for a room with the given records.
+	Compact(roomTopic string, records []Record) error
+	Close() error
+}
+
+const walExt = ".wal"
+
+// FileStore is an append-only write-ahead log with one JSON-lines file per
+// room. File names may be shortened (see filename.For); the room is read
+// back from the records.
+type FileStore struct {
+	dir    string
+	mu     sync.Mutex
+	files  map[string]*os.File
+	logger *slog.Logger
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create buffer dir: %w", err)
+	}
+	return &FileStore{
+		dir:    dir,
+		files:  make(map[string]*os.File),
+		logger: logging.For(logging.Buffer),
+	}, nil
+}
+
+func (s *FileStore) roomPath(roomTopic string) string {
+	return filepath.Join(s.dir, filename.For(roomTopic)+walExt)
+}
+
+func (s *FileStore) Load() ([]Record, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read buffer dir: %w", err)
+	}
+
+	var records []Record
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), walExt) {
+			continue
+		}
+		roomRecords, err := s.readWAL(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, roomRecords...)
+	}
+	return records, nil
+}
+
+func (s *FileStore) readWAL(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// A crash mid-write leaves a torn last line; skip it rather than
+			// refusing to start.
+			s.logger.Warn("skipping corrupt record", "file", path, "line", line, "err", err)
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return records, nil
+}
+
+func (s *FileStore) Append(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[rec.RoomTopic]
+	if !ok {
+		f, err = os.OpenFile(s.roomPath(rec.RoomTopic), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open wal: %w", err)
+		}
+		s.files[rec.RoomTopic] = f
+	}
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to append record: %w", err)
+	}
+	// Sync so that buffered messages also survive a power loss, not only a
+	// crash of the process.
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync wal: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Compact(roomTopic string, records []Record) error {
+	path := s.roomPath(roomTopic)
+	tmp, err := os.CreateTemp(s.dir, ".compact-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp wal: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to encode record: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp wal: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp wal: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp wal: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.files[roomTopic]; ok {
+		f.Close()
+		delete(s.files, roomTopic)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace wal: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for room, f := range s.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.files, room)
+	}
+	return firstErr
+}
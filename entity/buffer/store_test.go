@@ -0,0 +1,209 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreReplay(t *testing.T) {
+	tests := []struct {
+		name     string
+		room     string
+		capacity int
+		apply    func(b *MessageBuffer, room string)
+		want     []string
+		// maxRecords bounds the WAL length, checking compaction.
+		maxRecords int
+	}{
+		{
+			name:     "adds",
+			room:     "room",
+			capacity: 10,
+			apply:    func(b *MessageBuffer, room string) { addMessages(b, room, 1, 3) },
+			want:     []string{"m1", "m2", "m3"},
+		},
+		{
+			name:     "duplicates",
+			room:     "room",
+			capacity: 10,
+			apply: func(b *MessageBuffer, room string) {
+				addMessages(b, room, 1, 2)
+				addMessages(b, room, 2, 3)
+			},
+			want:       []string{"m1", "m2", "m3"},
+			maxRecords: 3,
+		},
+		{
+			name:     "clear",
+			room:     "room",
+			capacity: 10,
+			apply: func(b *MessageBuffer, room string) {
+				addMessages(b, room, 1, 3)
+				b.Clear(room)
+				addMessages(b, room, 4, 4)
+			},
+			want:       []string{"m4"},
+			maxRecords: 2,
+		},
+		{
+			name:     "clear up to",
+			room:     "room",
+			capacity: 10,
+			apply: func(b *MessageBuffer, room string) {
+				addMessages(b, room, 1, 3)
+				cursor := b.GetSnapshot(room).Cursor
+				addMessages(b, room, 4, 5)
+				b.ClearUpTo(cursor)
+			},
+			want: []string{"m4", "m5"},
+			// The summary time and the two kept messages.
+			maxRecords: 3,
+		},
+		{
+			name:     "ring compaction",
+			room:     "room",
+			capacity: 2,
+			apply:    func(b *MessageBuffer, room string) { addMessages(b, room, 1, 9) },
+			want:     []string{"m8", "m9"},
+			// Compaction runs once the log exceeds twice the capacity.
+			maxRecords: 4,
+		},
+		{
+			name:     "long room name",
+			room:     strings.Repeat("产品周会", 30),
+			capacity: 10,
+			apply:    func(b *MessageBuffer, room string) { addMessages(b, room, 1, 2) },
+			want:     []string{"m1", "m2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setBufferSize(t, tt.capacity)
+			dir := t.TempDir()
+			b := openFileBuffer(t, dir)
+			tt.apply(b, tt.room)
+			b.Close()
+
+			restored := openFileBuffer(t, dir)
+			defer restored.Close()
+			if got := messageIDs(restored.Messages(tt.room, Filter{})); !slices.Equal(got, tt.want) {
+				t.Errorf("restored %v, want %v", got, tt.want)
+			}
+			if tt.maxRecords > 0 {
+				if n := walRecords(t, dir); n > tt.maxRecords {
+					t.Errorf("wal holds %d records, want at most %d", n, tt.maxRecords)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreSkipsTornRecord(t *testing.T) {
+	setBufferSize(t, 10)
+	dir := t.TempDir()
+	b := openFileBuffer(t, dir)
+	addMessages(b, "room", 1, 2)
+	b.Close()
+
+	// A crash mid-write leaves a torn last line.
+	appendWAL(t, dir, `{"op":"add","room":"ro`)
+
+	restored := openFileBuffer(t, dir)
+	defer restored.Close()
+	if got := messageIDs(restored.Messages("room", Filter{})); !slices.Equal(got, []string{"m1", "m2"}) {
+		t.Errorf("restored %v, want [m1 m2]", got)
+	}
+}
+
+func TestFileStoreReplayUpdates(t *testing.T) {
+	setBufferSize(t, 10)
+	dir := t.TempDir()
+	b := openFileBuffer(t, dir)
+	addMessages(b, "room", 1, 2)
+	updated := testMessage("room", 2)
+	updated.Content = "transcript"
+	if !b.Update(updated) {
+		t.Fatal("Update returned false for a buffered message")
+	}
+	if b.Update(testMessage("room", 3)) {
+		t.Error("Update returned true for a message that was never buffered")
+	}
+	b.Close()
+
+	restored := openFileBuffer(t, dir)
+	defer restored.Close()
+	msgs := restored.Messages("room", Filter{})
+	if got := messageIDs(msgs); !slices.Equal(got, []string{"m1", "m2"}) {
+		t.Fatalf("restored %v, want [m1 m2]", got)
+	}
+	if msgs[1].Content != "transcript" {
+		t.Errorf("restored content %q, want the update", msgs[1].Content)
+	}
+}
+
+func TestFileStoreReplayRollingSummary(t *testing.T) {
+	setBufferSize(t, 10)
+	dir := t.TempDir()
+	b := openFileBuffer(t, dir)
+	addMessages(b, "room", 1, 2)
+	b.SetRollingSummary("room", RollingState{Summary: "minutes so far", MessageCount: 2})
+	b.Close()
+
+	restored := openFileBuffer(t, dir)
+	defer restored.Close()
+	if state := restored.RollingSummary("room"); state == nil || state.Summary != "minutes so far" || state.MessageCount != 2 {
+		t.Errorf("restored rolling summary %+v", state)
+	}
+	if state := restored.RollingSummary("other"); state != nil {
+		t.Errorf("rolling summary %+v for a room that never had one", state)
+	}
+}
+
+func openFileBuffer(t *testing.T, dir string) *MessageBuffer {
+	t.Helper()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewWithStore(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func walFile(t *testing.T, dir string) string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+walExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("found wal files %v, want one", files)
+	}
+	return files[0]
+}
+
+func walRecords(t *testing.T, dir string) int {
+	t.Helper()
+	data, err := os.ReadFile(walFile(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
+
+func appendWAL(t *testing.T, dir, line string) {
+	t.Helper()
+	f, err := os.OpenFile(walFile(t, dir), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"io"
+	"time"
+)
+
+// Message is an inbound group message, already resolved to its room and sender.
+type Message struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Room   string    `json:"room"`
+	Sender string    `json:"sender"`
+	// Content is the text of text and quote messages, and the description
+	// of links; it may be empty for other kinds.
+	Content string `json:"content"`
+	Kind    Kind   `json:"kind,omitempty"`
+	Meta    *Meta  `json:"meta,omitempty"`
+	// Fetch, when set, downloads the media of image, voice, video and file
+	// messages.
+	Fetch func() (io.ReadCloser, error) `json:"-"`
+}
+
+// Kind is the type of a message. The empty kind is text.
+type Kind string
+
+const (
+	KindText  Kind = "text"
+	KindImage Kind = "image"
+	KindFile  Kind = "file"
+	KindLink  Kind = "link"
+	KindQuote Kind = "quote"
+	KindVoice Kind = "voice"
+	KindVideo Kind = "video"
+)
+
+// Meta describes the non-text part of a message.
+type Meta struct {
+	FileName string `json:"file_name,omitempty"`
+	FileSize int64  `json:"file_size,omitempty"`
+	Title    string `json:"title,omitempty"`
+	URL      string `json:"url,omitempty"`
+	// Seconds is the length of voice and video messages.
+	Seconds int    `json:"seconds,omitempty"`
+	Quote   *Quote `json:"quote,omitempty"`
+	// Path is the local copy of the media, once downloaded.
+	Path string `json:"path,omitempty"`
+	// Transcribed marks voice messages whose Content is a speech-to-text
+	// transcript.
+	Transcribed bool `json:"transcribed,omitempty"`
+}
+
+// Quote is the message a reply refers to.
+type Quote struct {
+	ID      string `json:"id,omitempty"`
+	Sender  string `json:"sender"`
+	Content string `json:"content"`
+}
+
+type Handler func(msg Message)
+
+type DestinationKind int
+
+const (
+	// ToSelf is the account's own inbox (FileHelper on WeChat).
+	ToSelf DestinationKind = iota
+	ToRoom
+	ToFriend
+)
+
+// Destination names where outbound text is delivered. Name is ignored for ToSelf.
+type Destination struct {
+	Kind DestinationKind
+	Name string
+}
+
+func Self() Destination {
+	return Destination{Kind: ToSelf}
+}
+
+func Room(name string) Destination {
+	return Destination{Kind: ToRoom, Name: name}
+}
+
+func Friend(name string) Destination {
+	return Destination{Kind: ToFriend, Name: name}
+}
+
+func (d Destination) String() string {
+	switch d.Kind {
+	case ToSelf:
+		return "self"
+	case ToRoom:
+		return "room:" + d.Name
+	case ToFriend:
+		return "friend:" + d.Name
+	default:
+		return "unknown:" + d.Name
+	}
+}
+
+// Source delivers inbound group messages.
+type Source interface {
+	// Start logs in and begins calling handler for every group message.
+	Start(handler Handler) error
+	// Block waits until the source disconnects or Stop is called.
+	Block() error
+	Stop()
+}
+
+// Sender delivers outbound text.
+type Sender interface {
+	SendText(dest Destination, text string) error
+}
+
+type Platform interface {
+	Source
+	Sender
+}
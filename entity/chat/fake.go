@@ -0,0 +1,140 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+)
+
+// Outgoing is a message recorded by Fake.SendText.
+type Outgoing struct {
+	Destination Destination
+	Text        string
+}
+
+// Fake is an in-process Platform for tests and local demos. Messages are
+// injected with Deliver (or read from Input) and outbound text is recorded.
+type Fake struct {
+	// Input, when set, is read line by line as by Feed.
+	Input io.Reader
+	// Output, when set, receives a copy of every outbound message.
+	Output io.Writer
+
+	mu       sync.Mutex
+	handler  Handler
+	sent     []Outgoing
+	nextID   int
+	done     chan struct{}
+	stopOnce sync.Once
+	logger   *slog.Logger
+}
+
+func NewFake() *Fake {
+	return &Fake{
+		done:   make(chan struct{}),
+		logger: logging.For(logging.Chat).With("platform", "fake"),
+	}
+}
+
+func (f *Fake) Start(handler Handler) error {
+	f.mu.Lock()
+	f.handler = handler
+	f.mu.Unlock()
+
+	if f.Input != nil {
+		go func() {
+			if err := f.Feed(f.Input); err != nil {
+				f.logger.Error("failed to read input", "err", err)
+			}
+		}()
+	}
+	return nil
+}
+
+func (f *Fake) Block() error {
+	<-f.done
+	return nil
+}
+
+func (f *Fake) Stop() {
+	f.stopOnce.Do(func() {
+		close(f.done)
+	})
+}
+
+// Deliver passes msg to the registered handler, filling in ID and Time when empty.
+func (f *Fake) Deliver(msg Message) {
+	f.mu.Lock()
+	handler := f.handler
+	f.nextID++
+	if msg.ID == "" {
+		msg.ID = "fake-" + strconv.Itoa(f.nextID)
+	}
+	f.mu.Unlock()
+
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	if handler != nil {
+		handler(msg)
+	}
+}
+
+// Feed delivers one message per line of r. A line is either
+// "room|sender|content" or a JSON-encoded Message, which can carry any kind.
+func (f *Fake) Feed(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "{") {
+			var msg Message
+			if err := json.Unmarshal([]byte(line), &msg); err != nil {
+				f.logger.Warn("ignoring malformed JSON line", "line", line, "err", err)
+				continue
+			}
+			f.Deliver(msg)
+			continue
+		}
+		parts := strings.SplitN(line, "|", 3)
+		if len(parts) != 3 {
+			f.logger.Warn("ignoring malformed line, want room|sender|content", "line", line)
+			continue
+		}
+		f.Deliver(Message{
+			Room:    strings.TrimSpace(parts[0]),
+			Sender:  strings.TrimSpace(parts[1]),
+			Content: parts[2],
+		})
+	}
+	return scanner.Err()
+}
+
+func (f *Fake) SendText(dest Destination, text string) error {
+	f.mu.Lock()
+	f.sent = append(f.sent, Outgoing{Destination: dest, Text: text})
+	out := f.Output
+	f.mu.Unlock()
+
+	if out != nil {
+		fmt.Fprintf(out, "\n=== to %s ===\n%s\n", dest, text)
+	}
+	return nil
+}
+
+// Sent returns a copy of all outbound messages so far.
+func (f *Fake) Sent() []Outgoing {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Outgoing(nil), f.sent...)
+}
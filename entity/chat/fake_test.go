@@ -0,0 +1,81 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFakeFeed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Message
+	}{
+		{
+			name:  "pipe lines",
+			input: "周会|alice|早上好\n\n周会 | bob |内容|带竖线\n",
+			want: []Message{
+				{ID: "fake-1", Room: "周会", Sender: "alice", Content: "早上好"},
+				{ID: "fake-2", Room: "周会", Sender: "bob", Content: "内容|带竖线"},
+			},
+		},
+		{
+			name:  "json line",
+			input: `{"id":"img-1","room":"周会","sender":"alice","kind":"image","meta":{"file_name":"a.png"}}`,
+			want: []Message{
+				{ID: "img-1", Room: "周会", Sender: "alice", Kind: KindImage, Meta: &Meta{FileName: "a.png"}},
+			},
+		},
+		{
+			name:  "malformed lines skipped",
+			input: "no separators\n{not json\n周会|alice|ok",
+			want:  []Message{{ID: "fake-1", Room: "周会", Sender: "alice", Content: "ok"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFake()
+			var got []Message
+			f.Start(func(msg Message) { got = append(got, msg) })
+
+			if err := f.Feed(strings.NewReader(tt.input)); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("delivered %d messages, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, msg := range got {
+				want := tt.want[i]
+				if msg.ID != want.ID || msg.Room != want.Room || msg.Sender != want.Sender ||
+					msg.Content != want.Content || msg.Kind != want.Kind {
+					t.Errorf("message %d = %+v, want %+v", i, msg, want)
+				}
+				if (msg.Meta == nil) != (want.Meta == nil) || msg.Meta != nil && *msg.Meta != *want.Meta {
+					t.Errorf("message %d meta = %+v, want %+v", i, msg.Meta, want.Meta)
+				}
+				if msg.Time.IsZero() {
+					t.Errorf("message %d has no time", i)
+				}
+			}
+		})
+	}
+}
+
+func TestFakeSendText(t *testing.T) {
+	f := NewFake()
+	var out strings.Builder
+	f.Output = &out
+
+	f.SendText(Self(), "纪要")
+	f.SendText(Room("周会"), "收到")
+
+	sent := f.Sent()
+	if len(sent) != 2 || sent[0] != (Outgoing{Destination: Self(), Text: "纪要"}) ||
+		sent[1] != (Outgoing{Destination: Room("周会"), Text: "收到"}) {
+		t.Errorf("sent %+v", sent)
+	}
+	if !strings.Contains(out.String(), "收到") {
+		t.Errorf("output %q does not echo the messages", out.String())
+	}
+}
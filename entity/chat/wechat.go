@@ -0,0 +1,158 @@
+package chat
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/eatmoreapple/openwechat"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+)
+
+const hotReloadStorageFile = "storage.json"
+
+// WeChat adapts an openwechat desktop bot to Platform.
+type WeChat struct {
+	bot     *openwechat.Bot
+	self    *openwechat.Self
+	storage io.ReadWriteCloser
+	logger  *slog.Logger
+}
+
+func NewWeChat() *WeChat {
+	return &WeChat{
+		bot:    openwechat.DefaultBot(openwechat.Desktop),
+		logger: logging.For(logging.Chat).With("platform", "wechat"),
+	}
+}
+
+func (w *WeChat) Start(handler Handler) error {
+	w.bot.UUIDCallback = openwechat.PrintlnQrcodeUrl
+	w.bot.MessageHandler = func(msg *openwechat.Message) {
+		if m, ok := w.convert(msg); ok {
+			handler(m)
+		}
+	}
+
+	w.storage = openwechat.NewFileHotReloadStorage(hotReloadStorageFile)
+
+	w.logger.Info("attempting hot login")
+	if err := w.bot.PushLogin(w.storage, openwechat.NewRetryLoginOption()); err != nil {
+		w.storage.Close()
+		return fmt.Errorf("login failed: %w", err)
+	}
+
+	self, err := w.bot.GetCurrentUser()
+	if err != nil {
+		w.storage.Close()
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	w.self = self
+
+	w.logger.Info("logged in", "user", self.NickName)
+	return nil
+}
+
+func (w *WeChat) convert(msg *openwechat.Message) (Message, bool) {
+	if msg.IsSendBySelf() {
+		return Message{}, false
+	}
+	kind, content, meta, ok := classify(msg)
+	if !ok {
+		return Message{}, false
+	}
+
+	sender, err := msg.Sender()
+	if err != nil {
+		w.logger.Error("failed to get message sender", "msg_id", msg.MsgId, "err", err)
+		return Message{}, false
+	}
+
+	if !sender.IsGroup() {
+		return Message{}, false
+	}
+
+	group := openwechat.Group{User: sender}
+
+	senderUser, err := msg.SenderInGroup()
+	if err != nil {
+		w.logger.Error("failed to get sender in group", "msg_id", msg.MsgId, "err", err)
+		return Message{}, false
+	}
+
+	m := Message{
+		ID:      msg.MsgId,
+		Time:    time.Now(),
+		Room:    group.NickName,
+		Sender:  senderUser.NickName,
+		Content: content,
+		Kind:    kind,
+		Meta:    meta,
+	}
+	if msg.HasFile() {
+		m.Fetch = func() (io.ReadCloser, error) {
+			resp, err := msg.GetFile()
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				return nil, fmt.Errorf("download failed: %s", resp.Status)
+			}
+			return resp.Body, nil
+		}
+	}
+	return m, true
+}
+
+func (w *WeChat) Block() error {
+	defer w.storage.Close()
+	return w.bot.Block()
+}
+
+func (w *WeChat) Stop() {
+	if w.bot.Alive() {
+		w.bot.Exit()
+	}
+}
+
+func (w *WeChat) SendText(dest Destination, text string) error {
+	if w.self == nil {
+		return fmt.Errorf("self user not available")
+	}
+
+	switch dest.Kind {
+	case ToSelf:
+		_, err := w.self.FileHelper().SendText(text)
+		return err
+	case ToRoom:
+		groups, err := w.self.Groups()
+		if err != nil {
+			return fmt.Errorf("failed to list groups: %w", err)
+		}
+		group := groups.GetByNickName(dest.Name)
+		if group == nil {
+			return fmt.Errorf("group '%s' not found", dest.Name)
+		}
+		_, err = group.SendText(text)
+		return err
+	case ToFriend:
+		friends, err := w.self.Friends()
+		if err != nil {
+			return fmt.Errorf("failed to list friends: %w", err)
+		}
+		friend := friends.GetByRemarkName(dest.Name)
+		if friend == nil {
+			friend = friends.GetByNickName(dest.Name)
+		}
+		if friend == nil {
+			return fmt.Errorf("friend '%s' not found", dest.Name)
+		}
+		_, err = friend.SendText(text)
+		return err
+	default:
+		return fmt.Errorf("unsupported destination %s", dest)
+	}
+}
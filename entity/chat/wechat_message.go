@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/eatmoreapple/openwechat"
+)
+
+// appMsgTypeRefer is the app message type of a quoted reply.
+const appMsgTypeRefer openwechat.AppMessageType = 57
+
+// appMessage is the XML body of an app message (links, files, quotes).
+type appMessage struct {
+	AppMsg struct {
+		Title     string                    `xml:"title"`
+		Des       string                    `xml:"des"`
+		Type      openwechat.AppMessageType `xml:"type"`
+		URL       string                    `xml:"url"`
+		AppAttach struct {
+			TotalLen int64  `xml:"totallen"`
+			FileExt  string `xml:"fileext"`
+		} `xml:"appattach"`
+		ReferMsg struct {
+			SvrID       string `xml:"svrid"`
+			DisplayName string `xml:"displayname"`
+			Content     string `xml:"content"`
+		} `xml:"refermsg"`
+	} `xml:"appmsg"`
+}
+
+// Quoted replies sent from clients without app message support arrive as
+// text: 「sender：quoted」, a dashed separator line, then the reply.
+var quotedText = regexp.MustCompile(`(?s)^「(.+?)[：:](.*)」\n(?:- )+-?\n(.*)$`)
+
+// classify extracts the kind, text and metadata of msg. It returns false
+// for messages that carry nothing worth summarizing, such as stickers and
+// system notices.
+func classify(msg *openwechat.Message) (Kind, string, *Meta, bool) {
+	switch {
+	case msg.IsText():
+		if m := quotedText.FindStringSubmatch(msg.Content); m != nil {
+			return KindQuote, m[3], &Meta{Quote: &Quote{Sender: m[1], Content: m[2]}}, true
+		}
+		return KindText, msg.Content, nil, true
+	case msg.IsPicture():
+		return KindImage, "", nil, true
+	case msg.IsVoice():
+		return KindVoice, "", &Meta{Seconds: msg.VoiceLength / 1000}, true
+	case msg.IsVideo():
+		return KindVideo, "", &Meta{Seconds: int(msg.PlayLength)}, true
+	case msg.IsMedia():
+		return classifyApp(msg)
+	default:
+		return "", "", nil, false
+	}
+}
+
+func classifyApp(msg *openwechat.Message) (Kind, string, *Meta, bool) {
+	var data appMessage
+	if err := xml.Unmarshal([]byte(msg.Content), &data); err != nil {
+		return "", "", nil, false
+	}
+	app := data.AppMsg
+
+	switch app.Type {
+	case openwechat.AppMsgTypeAttach:
+		size := app.AppAttach.TotalLen
+		if size == 0 {
+			size, _ = strconv.ParseInt(msg.FileSize, 10, 64)
+		}
+		name := app.Title
+		if name == "" {
+			name = msg.FileName
+		}
+		return KindFile, "", &Meta{FileName: name, FileSize: size}, true
+	case openwechat.AppMsgTypeUrl:
+		return KindLink, strings.TrimSpace(app.Des), &Meta{Title: app.Title, URL: app.URL}, true
+	case appMsgTypeRefer:
+		quote := &Quote{
+			ID:      app.ReferMsg.SvrID,
+			Sender:  app.ReferMsg.DisplayName,
+			Content: app.ReferMsg.Content,
+		}
+		return KindQuote, app.Title, &Meta{Quote: quote}, true
+	default:
+		return "", "", nil, false
+	}
+}
@@ -2,12 +2,13 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
-
-	"github.com/joho/godotenv"
+	"sync/atomic"
+	"time"
 )
 
 type SummaryTriggerConfig struct {
@@ -15,59 +16,237 @@ type SummaryTriggerConfig struct {
 	MessageCount          int
 	Keyword               string
 	MinMessagesForSummary int
+	// Schedule is a cron expression; when set it replaces the interval trigger.
+	Schedule string
+}
+
+// LLMProvider is an OpenAI-compatible endpoint in the fallback chain.
+type LLMProvider struct {
+	BaseURL string
+	APIKey  string
+	Model   string
 }
 
 type Config struct {
-	LLMAPIKey        string
-	LLMBaseURL       string
-	LLMModel         string
-	SystemPromptFile string
+	LLMAPIKey     string
+	LLMBaseURL    string
+	LLMModel      string
+	LLMTimeout    time.Duration
+	LLMMaxRetries int
+	LLMRetryBase  time.Duration
+	LLMRetryMax   time.Duration
+	// LLMFallbacks are tried in order when the primary provider fails.
+	LLMFallbacks []LLMProvider
+	// LLMVision sends downloaded images along with the transcript; the
+	// model must accept image input.
+	LLMVision           bool
+	MaxImagesPerSummary int
+	MaxImageBytes       int64
+	SystemPromptFile    string
+	// PromptTemplateFile is a text/template for the user prompt; empty uses
+	// the built-in one.
+	PromptTemplateFile string
+	// SummaryLanguage is the {{.Language}} of prompt templates.
+	SummaryLanguage string
+	// SummaryStrategy is "auto", "single" or "mapreduce".
+	SummaryStrategy    string
+	SummaryChunkTokens int
+	// SummaryMode is "reset" (each summary covers only its own messages) or
+	// "rolling" (each summary updates the room's minutes for the day).
+	SummaryMode      string
+	RollingResetHour int
+	// SummaryOutput is "text" or "structured" (typed minutes rendered to Markdown).
+	SummaryOutput string
+	// LLMJSONMode is how structured output is requested: "schema"
+	// (json_schema response format), "object" (json_object) or "prompt"
+	// (instructions only, for providers without a JSON mode).
+	LLMJSONMode      string
 	BotName          string
+	ChatPlatform     string
 	TargetRooms      []string
 	SummaryTrigger   SummaryTriggerConfig
 	MaxBufferSize    int
+	BufferPersistDir string
+	// MediaDir receives downloaded images and voice messages; empty
+	// disables downloads.
+	MediaDir string
+	// MediaRetention is how long downloaded media is kept; zero keeps it
+	// forever.
+	MediaRetention time.Duration
+	// STTProvider is "none", "openai" or "fake".
+	STTProvider string
+	STTBaseURL  string
+	STTAPIKey   string
+	STTModel    string
+	STTLanguage string
+	STTTimeout  time.Duration
+	ArchiveFile string
+	// HistoryRetention is how long summarized messages stay in the archive
+	// for on-demand queries; zero keeps none.
+	HistoryRetention time.Duration
 	SummaryQueueSize int
+	SummaryWorkers   int
+	DeliverTo        Targets
+	// CommandPrefix addresses chat commands to the bot; empty disables them.
+	CommandPrefix string
+	// BotOwners are the sender names allowed to run OwnerCommands.
+	BotOwners     []string
+	OwnerCommands []string
+	// CommandReplyTo is "room" or "self".
+	CommandReplyTo  string
+	RoomsConfigFile string
+	RoomOverrides   []RoomOverride
+	AdminAddr       string
+	AdminToken      string
+	MetricsAddr     string
+	LogFormat       string
+	LogLevel        string
+	// LogLevels overrides LogLevel per subsystem, e.g. "buffer=debug,llm=warn".
+	LogLevels string
+	// ScheduleLocation is the time zone of cron schedules without CRON_TZ.
+	ScheduleLocation *time.Location
+	// ScheduleCatchUp runs schedules missed while the bot was down.
+	ScheduleCatchUp bool
+	// DigestSchedule is the cron expression of the cross-room digest;
+	// empty disables it.
+	DigestSchedule string
+	// DigestPeriod is "daily" or "weekly".
+	DigestPeriod    string
+	DigestDeliverTo Targets
+	// ConfigWatch reloads the configuration when .env or the rooms file
+	// changes.
+	ConfigWatch bool
+	// Version counts successful loads, starting at 1.
+	Version int
+	// RestartPending lists the variables whose new value was ignored by the
+	// last reload because they are only read at startup.
+	RestartPending []string
 }
 
-var AppConfig *Config
+var current atomic.Pointer[Config]
+
+// Current returns the configuration in effect. Callers that read several
+// fields for one decision should call it once, so a concurrent reload
+// cannot mix two versions.
+func Current() *Config {
+	return current.Load()
+}
+
+// Set makes c the current configuration. It is meant for tools such as
+// replay that adjust the loaded configuration before running.
+func Set(c *Config) {
+	current.Store(c)
+}
 
 func Load() error {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
+	env.processKeys = make(map[string]bool)
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		env.processKeys[key] = true
+	}
+	found, err := loadEnvFile()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", EnvFile, err)
+	}
+	if !found {
+		slog.Info("no .env file found, using environment variables")
+	}
+
+	c, err := read()
+	if err != nil {
+		return err
 	}
+	c.Version = 1
+	current.Store(c)
+	return nil
+}
 
-	AppConfig = &Config{
-		LLMAPIKey:        getEnv("LLM_API_KEY", ""),
-		LLMBaseURL:       getEnv("LLM_BASE_URL", "https://generativelanguage.googleapis.com/v1beta/openai/"),
-		LLMModel:         getEnv("LLM_MODEL", "gemini-2.5-flash"),
-		SystemPromptFile: getEnv("SYSTEM_PROMPT_FILE", "system_prompt.txt"),
-		BotName:          getEnv("BOT_NAME", "meeting-minutes-bot"),
+// read builds and validates a configuration from the environment and the
+// rooms file.
+func read() (*Config, error) {
+	c := &Config{
+		LLMAPIKey:           getEnv("LLM_API_KEY", ""),
+		LLMBaseURL:          getEnv("LLM_BASE_URL", "https://generativelanguage.googleapis.com/v1beta/openai/"),
+		LLMModel:            getEnv("LLM_MODEL", "gemini-2.5-flash"),
+		LLMTimeout:          time.Duration(getEnvInt("LLM_TIMEOUT_SECONDS", 120)) * time.Second,
+		LLMMaxRetries:       getEnvInt("LLM_MAX_RETRIES", 3),
+		LLMRetryBase:        time.Duration(getEnvInt("LLM_RETRY_BASE_MS", 1000)) * time.Millisecond,
+		LLMRetryMax:         time.Duration(getEnvInt("LLM_RETRY_MAX_MS", 30000)) * time.Millisecond,
+		LLMVision:           getEnvBool("LLM_VISION", false),
+		MaxImagesPerSummary: getEnvInt("MAX_IMAGES_PER_SUMMARY", 4),
+		MaxImageBytes:       int64(getEnvInt("MAX_IMAGE_KB", 5120)) * 1024,
+		SystemPromptFile:    getEnv("SYSTEM_PROMPT_FILE", "system_prompt.txt"),
+		PromptTemplateFile:  getEnv("PROMPT_TEMPLATE_FILE", ""),
+		SummaryLanguage:     getEnv("SUMMARY_LANGUAGE", "中文"),
+		SummaryStrategy:     getEnv("SUMMARY_STRATEGY", "auto"),
+		SummaryChunkTokens:  getEnvInt("SUMMARY_CHUNK_TOKENS", 8000),
+		SummaryMode:         getEnv("SUMMARY_MODE", "reset"),
+		RollingResetHour:    getEnvInt("ROLLING_RESET_HOUR", 0),
+		SummaryOutput:       getEnv("SUMMARY_OUTPUT", "text"),
+		LLMJSONMode:         getEnv("LLM_JSON_MODE", "schema"),
+		BotName:             getEnv("BOT_NAME", "meeting-minutes-bot"),
+		ChatPlatform:        getEnv("CHAT_PLATFORM", "wechat"),
 		SummaryTrigger: SummaryTriggerConfig{
 			IntervalMinutes:       getEnvInt("SUMMARY_INTERVAL_MINUTES", 30),
 			MessageCount:          getEnvInt("SUMMARY_MESSAGE_COUNT", 50),
 			Keyword:               getEnv("SUMMARY_KEYWORD", "@bot 总结"),
 			MinMessagesForSummary: getEnvInt("MIN_MESSAGES_FOR_SUMMARY", 5),
+			Schedule:              getEnv("SUMMARY_SCHEDULE", ""),
 		},
 		MaxBufferSize:    getEnvInt("MAX_BUFFER_SIZE", 200),
+		BufferPersistDir: getEnv("BUFFER_PERSIST_DIR", ""),
+		MediaDir:         getEnv("MEDIA_DIR", ""),
+		MediaRetention:   time.Duration(getEnvInt("MEDIA_RETENTION_DAYS", 7)) * 24 * time.Hour,
+		STTProvider:      getEnv("STT_PROVIDER", "none"),
+		STTModel:         getEnv("STT_MODEL", "whisper-1"),
+		STTLanguage:      getEnvAllowEmpty("STT_LANGUAGE", "zh"),
+		STTTimeout:       time.Duration(getEnvInt("STT_TIMEOUT_SECONDS", 60)) * time.Second,
+		ArchiveFile:      getEnv("ARCHIVE_FILE", ""),
+		HistoryRetention: time.Duration(getEnvInt("HISTORY_RETENTION_DAYS", 7)) * 24 * time.Hour,
 		SummaryQueueSize: getEnvInt("CONCURRENT_SUMMARY", 10),
+		SummaryWorkers:   getEnvInt("SUMMARY_WORKERS", 2),
+		RoomsConfigFile:  getEnv("ROOMS_CONFIG_FILE", ""),
+		CommandPrefix:    getEnvAllowEmpty("COMMAND_PREFIX", "@bot"),
+		CommandReplyTo:   getEnv("COMMAND_REPLY_TO", "room"),
+		AdminAddr:        getEnv("ADMIN_ADDR", ""),
+		AdminToken:       getEnv("ADMIN_TOKEN", ""),
+		MetricsAddr:      getEnv("METRICS_ADDR", ""),
+		LogFormat:        getEnv("LOG_FORMAT", "text"),
+		LogLevel:         getEnv("LOG_LEVEL", "info"),
+		LogLevels:        getEnv("LOG_LEVELS", ""),
+		ScheduleCatchUp:  getEnvBool("SCHEDULE_CATCH_UP", true),
+		DigestSchedule:   getEnv("DIGEST_SCHEDULE", ""),
+		DigestPeriod:     getEnv("DIGEST_PERIOD", "daily"),
+		ConfigWatch:      getEnvBool("CONFIG_WATCH", true),
 	}
 
-	targetRoomsStr := getEnv("TARGET_ROOMS", "")
-	if targetRoomsStr != "" {
-		rooms := strings.Split(targetRoomsStr, ",")
-		for _, room := range rooms {
-			trimmed := strings.TrimSpace(room)
-			if trimmed != "" {
-				AppConfig.TargetRooms = append(AppConfig.TargetRooms, trimmed)
-			}
-		}
+	location, err := time.LoadLocation(getEnv("SCHEDULE_TIMEZONE", "Local"))
+	if err != nil {
+		return nil, fmt.Errorf("SCHEDULE_TIMEZONE: %w", err)
 	}
+	c.ScheduleLocation = location
 
-	if err := AppConfig.validate(); err != nil {
-		return err
+	if c.RoomsConfigFile != "" {
+		overrides, err := loadRoomOverrides(c.RoomsConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		c.RoomOverrides = overrides
 	}
 
-	return nil
+	c.LLMFallbacks = getEnvProviders("LLM_FALLBACK_", c)
+	c.STTBaseURL = getEnv("STT_BASE_URL", c.LLMBaseURL)
+	c.STTAPIKey = getEnv("STT_API_KEY", c.LLMAPIKey)
+	c.TargetRooms = getEnvList("TARGET_ROOMS", "")
+	c.DeliverTo = getEnvList("DELIVER_TO", "self")
+	c.DigestDeliverTo = getEnvList("DIGEST_DELIVER_TO", "self")
+	c.BotOwners = getEnvList("BOT_OWNERS", "")
+	c.OwnerCommands = getEnvList("OWNER_COMMANDS", "pause,resume,clear")
+
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 func (c *Config) validate() error {
@@ -77,42 +256,218 @@ func (c *Config) validate() error {
 	if c.SystemPromptFile == "" {
 		return fmt.Errorf("SYSTEM_PROMPT_FILE is required")
 	}
+	if c.LLMTimeout <= 0 {
+		return fmt.Errorf("LLM_TIMEOUT_SECONDS must be positive, got %v", c.LLMTimeout)
+	}
+	if c.LLMMaxRetries < 0 {
+		return fmt.Errorf("LLM_MAX_RETRIES must not be negative, got %d", c.LLMMaxRetries)
+	}
+	if c.LLMRetryBase <= 0 || c.LLMRetryMax < c.LLMRetryBase {
+		return fmt.Errorf("LLM_RETRY_BASE_MS must be positive and not above LLM_RETRY_MAX_MS")
+	}
+	switch c.SummaryStrategy {
+	case "auto", "single", "mapreduce":
+	default:
+		return fmt.Errorf("SUMMARY_STRATEGY must be 'auto', 'single' or 'mapreduce', got '%s'", c.SummaryStrategy)
+	}
+	if c.SummaryChunkTokens <= 0 {
+		return fmt.Errorf("SUMMARY_CHUNK_TOKENS must be positive, got %d", c.SummaryChunkTokens)
+	}
+	if c.SummaryMode != "reset" && c.SummaryMode != "rolling" {
+		return fmt.Errorf("SUMMARY_MODE must be 'reset' or 'rolling', got '%s'", c.SummaryMode)
+	}
+	if c.MaxBufferSize <= 0 {
+		return fmt.Errorf("MAX_BUFFER_SIZE must be positive, got %d", c.MaxBufferSize)
+	}
+	if c.RollingResetHour < 0 || c.RollingResetHour > 23 {
+		return fmt.Errorf("ROLLING_RESET_HOUR must be between 0 and 23, got %d", c.RollingResetHour)
+	}
+	if c.MaxImagesPerSummary < 0 {
+		return fmt.Errorf("MAX_IMAGES_PER_SUMMARY must not be negative, got %d", c.MaxImagesPerSummary)
+	}
+	if c.MaxImageBytes <= 0 {
+		return fmt.Errorf("MAX_IMAGE_KB must be positive, got %d", c.MaxImageBytes/1024)
+	}
+	switch c.STTProvider {
+	case "none":
+	case "openai", "fake":
+		if c.STTTimeout <= 0 {
+			return fmt.Errorf("STT_TIMEOUT_SECONDS must be positive, got %v", c.STTTimeout)
+		}
+		// Recordings are transcribed from their download in MEDIA_DIR.
+		if c.MediaDir == "" {
+			return fmt.Errorf("STT_PROVIDER=%s requires MEDIA_DIR", c.STTProvider)
+		}
+	default:
+		return fmt.Errorf("STT_PROVIDER must be 'none', 'openai' or 'fake', got '%s'", c.STTProvider)
+	}
+	if c.MediaRetention < 0 {
+		return fmt.Errorf("MEDIA_RETENTION_DAYS must not be negative, got %d", int(c.MediaRetention.Hours()/24))
+	}
+	if c.HistoryRetention < 0 {
+		return fmt.Errorf("HISTORY_RETENTION_DAYS must not be negative, got %d", int(c.HistoryRetention.Hours()/24))
+	}
+	if c.SummaryTrigger.Schedule != "" {
+		if _, err := ParseSchedule(c.SummaryTrigger.Schedule); err != nil {
+			return fmt.Errorf("SUMMARY_SCHEDULE: %w", err)
+		}
+	}
 	if c.SummaryQueueSize <= 0 {
-		log.Printf("[Bot] Invalid SummaryQueueSize: %d", c.SummaryQueueSize)
+		return fmt.Errorf("CONCURRENT_SUMMARY must be positive, got %d", c.SummaryQueueSize)
+	}
+	if c.SummaryWorkers <= 0 {
+		return fmt.Errorf("SUMMARY_WORKERS must be positive, got %d", c.SummaryWorkers)
+	}
+	for _, name := range c.OwnerCommands {
+		switch name {
+		case "summary", "status", "pause", "resume", "clear", "help":
+		default:
+			return fmt.Errorf("OWNER_COMMANDS: unknown command '%s'", name)
+		}
+	}
+	if c.CommandReplyTo != "room" && c.CommandReplyTo != "self" {
+		return fmt.Errorf("COMMAND_REPLY_TO must be 'room' or 'self', got '%s'", c.CommandReplyTo)
+	}
+	if c.AdminAddr != "" && c.AdminToken == "" {
+		return fmt.Errorf("ADMIN_TOKEN is required when ADMIN_ADDR is set")
+	}
+	if len(c.DeliverTo) == 0 {
+		return fmt.Errorf("DELIVER_TO must name at least one target")
+	}
+	if err := c.DeliverTo.validate(); err != nil {
+		return fmt.Errorf("DELIVER_TO: %w", err)
+	}
+	if c.DigestSchedule != "" {
+		if _, err := ParseSchedule(c.DigestSchedule); err != nil {
+			return fmt.Errorf("DIGEST_SCHEDULE: %w", err)
+		}
+		if c.ArchiveFile == "" {
+			return fmt.Errorf("DIGEST_SCHEDULE requires ARCHIVE_FILE")
+		}
+	}
+	if c.DigestPeriod != "daily" && c.DigestPeriod != "weekly" {
+		return fmt.Errorf("DIGEST_PERIOD must be 'daily' or 'weekly', got '%s'", c.DigestPeriod)
+	}
+	if len(c.DigestDeliverTo) == 0 {
+		return fmt.Errorf("DIGEST_DELIVER_TO must name at least one target")
+	}
+	if slices.Contains(c.DigestDeliverTo, "room") {
+		return fmt.Errorf("DIGEST_DELIVER_TO cannot use 'room'; name the group with group:<name>")
+	}
+	if err := c.DigestDeliverTo.validate(); err != nil {
+		return fmt.Errorf("DIGEST_DELIVER_TO: %w", err)
+	}
+	for i, o := range c.RoomOverrides {
+		if err := o.validate(); err != nil {
+			return fmt.Errorf("%s: room #%d: %w", c.RoomsConfigFile, i+1, err)
+		}
+	}
+	if c.SummaryOutput != "text" && c.SummaryOutput != "structured" {
+		return fmt.Errorf("SUMMARY_OUTPUT must be 'text' or 'structured', got '%s'", c.SummaryOutput)
+	}
+	switch c.LLMJSONMode {
+	case "schema", "object", "prompt":
+	default:
+		return fmt.Errorf("LLM_JSON_MODE must be 'schema', 'object' or 'prompt', got '%s'", c.LLMJSONMode)
+	}
+	if c.ChatPlatform != "wechat" && c.ChatPlatform != "fake" {
+		return fmt.Errorf("CHAT_PLATFORM must be 'wechat' or 'fake', got '%s'", c.ChatPlatform)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("LOG_FORMAT must be 'text' or 'json', got '%s'", c.LogFormat)
 	}
 
-	log.Println("✓ Configuration loaded successfully")
-	log.Printf("  - Bot name: %s", c.BotName)
-	log.Printf("  - LLM base URL: %s", c.LLMBaseURL)
-	log.Printf("  - LLM model: %s", c.LLMModel)
-	log.Printf("  - System prompt file: %s", c.SystemPromptFile)
+	return nil
+}
 
+// Log writes the effective configuration, without secrets.
+func (c *Config) Log(logger *slog.Logger) {
+	targetRooms := "all"
 	if len(c.TargetRooms) > 0 {
-		log.Printf("  - Target rooms: %s", strings.Join(c.TargetRooms, ", "))
-	} else {
-		log.Println("  - Target rooms: All rooms")
+		targetRooms = strings.Join(c.TargetRooms, ", ")
 	}
 
-	log.Println("  - Summary triggers:")
-	if c.SummaryTrigger.IntervalMinutes > 0 {
-		log.Printf("    • Time-based: every %d minutes", c.SummaryTrigger.IntervalMinutes)
-	} else {
-		log.Println("    • Time-based: disabled")
+	logger.Info("configuration loaded",
+		"version", c.Version,
+		"bot_name", c.BotName,
+		"chat_platform", c.ChatPlatform,
+		"llm_base_url", c.LLMBaseURL,
+		"model", c.LLMModel,
+		"fallback_models", fallbackModels(c.LLMFallbacks),
+		"llm_timeout", c.LLMTimeout,
+		"llm_max_retries", c.LLMMaxRetries,
+		"llm_vision", c.LLMVision,
+		"media_dir", c.MediaDir,
+		"media_retention", c.MediaRetention,
+		"stt_provider", c.STTProvider,
+		"system_prompt_file", c.SystemPromptFile,
+		"prompt_template_file", c.PromptTemplateFile,
+		"summary_language", c.SummaryLanguage,
+		"summary_mode", c.SummaryMode,
+		"rolling_reset_hour", c.RollingResetHour,
+		"summary_output", c.SummaryOutput,
+		"json_mode", c.LLMJSONMode,
+		"summary_strategy", c.SummaryStrategy,
+		"chunk_tokens", c.SummaryChunkTokens,
+		"buffer_persist_dir", c.BufferPersistDir,
+		"archive_file", c.ArchiveFile,
+		"history_retention", c.HistoryRetention,
+		"summary_queue_size", c.SummaryQueueSize,
+		"summary_workers", c.SummaryWorkers,
+		"target_rooms", targetRooms,
+		"deliver_to", c.DeliverTo.String(),
+		"command_prefix", c.CommandPrefix,
+		"bot_owners", strings.Join(c.BotOwners, ", "),
+		"admin_addr", c.AdminAddr,
+		"metrics_addr", c.MetricsAddr,
+		"config_watch", c.ConfigWatch,
+	)
+	logger.Info("summary triggers",
+		"interval_minutes", c.SummaryTrigger.IntervalMinutes,
+		"message_count", c.SummaryTrigger.MessageCount,
+		"keyword", c.SummaryTrigger.Keyword,
+		"min_messages", c.SummaryTrigger.MinMessagesForSummary,
+		"schedule", c.SummaryTrigger.Schedule,
+		"schedule_timezone", c.ScheduleLocation.String(),
+		"max_buffer_size", c.MaxBufferSize,
+	)
+	if c.DigestSchedule != "" {
+		logger.Info("digest",
+			"schedule", c.DigestSchedule,
+			"period", c.DigestPeriod,
+			"deliver_to", c.DigestDeliverTo.String(),
+		)
 	}
-
-	if c.SummaryTrigger.MessageCount > 0 {
-		log.Printf("    • Volume-based: every %d messages", c.SummaryTrigger.MessageCount)
-	} else {
-		log.Println("    • Volume-based: disabled")
+	for _, o := range c.RoomOverrides {
+		logger.Info("room override", "match", o.Match, "file", c.RoomsConfigFile)
 	}
+}
 
-	if c.SummaryTrigger.Keyword != "" {
-		log.Printf("    • Keyword: %s", c.SummaryTrigger.Keyword)
-	} else {
-		log.Println("    • Keyword: disabled")
+func fallbackModels(providers []LLMProvider) []string {
+	models := make([]string, 0, len(providers))
+	for _, p := range providers {
+		models = append(models, p.Model)
 	}
+	return models
+}
 
-	return nil
+// getEnvProviders reads <prefix>1_MODEL, <prefix>1_BASE_URL, <prefix>1_API_KEY,
+// then <prefix>2_..., stopping at the first index without a model. A missing
+// base URL or key falls back to the primary provider's.
+func getEnvProviders(prefix string, primary *Config) []LLMProvider {
+	var providers []LLMProvider
+	for i := 1; ; i++ {
+		key := fmt.Sprintf("%s%d_", prefix, i)
+		model := getEnv(key+"MODEL", "")
+		if model == "" {
+			return providers
+		}
+		providers = append(providers, LLMProvider{
+			BaseURL: getEnv(key+"BASE_URL", primary.LLMBaseURL),
+			APIKey:  getEnv(key+"API_KEY", primary.LLMAPIKey),
+			Model:   model,
+		})
+	}
 }
 
 func getEnv(key, defaultValue string) string {
@@ -123,6 +478,41 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key, defaultValue string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		trimmed := strings.TrimSpace(item)
+		if trimmed != "" {
+			list = append(list, trimmed)
+		}
+	}
+	return list
+}
+
+// getEnvAllowEmpty treats an explicitly empty variable as a value, so that a
+// feature with a non-empty default can still be disabled.
+func getEnvAllowEmpty(key, defaultValue string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	return strings.TrimSpace(value)
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("invalid boolean value, using default", "key", key, "default", defaultValue)
+		return defaultValue
+	}
+	return boolValue
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	value := os.Getenv(key)
 	if value == "" {
@@ -130,7 +520,7 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	intValue, err := strconv.Atoi(value)
 	if err != nil {
-		log.Printf("Warning: Invalid integer value for %s, using default %d", key, defaultValue)
+		slog.Warn("invalid integer value, using default", "key", key, "default", defaultValue)
 		return defaultValue
 	}
 	return intValue
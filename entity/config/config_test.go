@@ -0,0 +1,183 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// readEnv reads a configuration from the minimal valid environment with
+// env applied on top of it.
+func readEnv(t *testing.T, env map[string]string) (*Config, error) {
+	t.Helper()
+	settings := map[string]string{
+		"LLM_API_KEY":        "test",
+		"SYSTEM_PROMPT_FILE": "system_prompt.txt",
+		"ROOMS_CONFIG_FILE":  "",
+		"DIGEST_SCHEDULE":    "",
+		"SUMMARY_SCHEDULE":   "",
+		"STT_PROVIDER":       "",
+		"MEDIA_DIR":          "",
+	}
+	for key, value := range env {
+		settings[key] = value
+	}
+	for key, value := range settings {
+		t.Setenv(key, value)
+	}
+	return read()
+}
+
+func TestReadValidates(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		// wantErr is contained in the error; empty for a valid config.
+		wantErr string
+	}{
+		{name: "defaults"},
+		{name: "missing api key", env: map[string]string{"LLM_API_KEY": ""}, wantErr: "LLM_API_KEY is required"},
+		{name: "stt needs media dir", env: map[string]string{"STT_PROVIDER": "openai"}, wantErr: "requires MEDIA_DIR"},
+		{name: "fake stt needs media dir", env: map[string]string{"STT_PROVIDER": "fake"}, wantErr: "requires MEDIA_DIR"},
+		{name: "stt with media dir", env: map[string]string{"STT_PROVIDER": "openai", "MEDIA_DIR": "media"}},
+		{
+			name: "stt timeout", env: map[string]string{"STT_PROVIDER": "openai", "MEDIA_DIR": "media", "STT_TIMEOUT_SECONDS": "0"},
+			wantErr: "STT_TIMEOUT_SECONDS",
+		},
+		{name: "unknown stt provider", env: map[string]string{"STT_PROVIDER": "whisper"}, wantErr: "STT_PROVIDER must be"},
+		{name: "schedule", env: map[string]string{"SUMMARY_SCHEDULE": "CRON_TZ=Asia/Shanghai 30 9 * * 1-5"}},
+		{name: "invalid schedule", env: map[string]string{"SUMMARY_SCHEDULE": "every morning"}, wantErr: "SUMMARY_SCHEDULE"},
+		{name: "seconds field rejected", env: map[string]string{"SUMMARY_SCHEDULE": "0 30 9 * * *"}, wantErr: "SUMMARY_SCHEDULE"},
+		{name: "unknown time zone", env: map[string]string{"SCHEDULE_TIMEZONE": "Mars/Olympus"}, wantErr: "SCHEDULE_TIMEZONE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readEnv(t, tt.env)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadSTTDefaults(t *testing.T) {
+	c, err := readEnv(t, map[string]string{
+		"STT_PROVIDER": "openai", "MEDIA_DIR": "media", "LLM_BASE_URL": "https://llm.example.com/v1/",
+		"STT_BASE_URL": "", "STT_API_KEY": "", "STT_LANGUAGE": "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// STT borrows the LLM endpoint unless it has its own, and an empty
+	// language lets the service detect it.
+	if c.STTBaseURL != "https://llm.example.com/v1/" || c.STTAPIKey != "test" || c.STTLanguage != "" {
+		t.Errorf("stt settings %q %q %q", c.STTBaseURL, c.STTAPIKey, c.STTLanguage)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Friday 2025-05-02 08:00 UTC, 16:00 in Shanghai.
+	from := time.Date(2025, 5, 2, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "zone of the reference time", spec: "30 9 * * 1-5", from: from,
+			want: time.Date(2025, 5, 2, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "schedule time zone", spec: "30 9 * * 1-5", from: from.In(shanghai),
+			want: time.Date(2025, 5, 5, 9, 30, 0, 0, shanghai),
+		},
+		{
+			name: "cron tz wins", spec: "CRON_TZ=Asia/Shanghai 0 17 * * *", from: from,
+			want: time.Date(2025, 5, 2, 17, 0, 0, 0, shanghai),
+		},
+		{name: "descriptor", spec: "@daily", from: from, want: time.Date(2025, 5, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	standup, other := "15 10 * * 1-5", "@daily"
+	c := &Config{
+		SummaryTrigger: SummaryTriggerConfig{Schedule: standup},
+		RoomOverrides: []RoomOverride{
+			{Match: "站会", Schedule: &standup},
+			{Match: "公告", Schedule: &other},
+			{Match: "闲聊"},
+		},
+	}
+	got := c.Schedules()
+	if len(got) != 2 || got[0] != standup || got[1] != other {
+		t.Errorf("Schedules() = %q, want each schedule once", got)
+	}
+}
+
+func TestReadRoomTemplate(t *testing.T) {
+	dir := t.TempDir()
+	template := filepath.Join(dir, "prompt_template.txt")
+	if err := os.WriteFile(template, []byte("{{.Messages}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{name: "existing", file: template},
+		{name: "missing", file: filepath.Join(dir, "missing.txt"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rooms := filepath.Join(t.TempDir(), "rooms.yaml")
+			yaml := "rooms:\n  - match: 周会\n    prompt_template_file: " + tt.file + "\n"
+			if err := os.WriteFile(rooms, []byte(yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := readEnv(t, map[string]string{"ROOMS_CONFIG_FILE": rooms})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "prompt_template_file") {
+					t.Errorf("err = %v, want a prompt_template_file error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.ForRoom("产品周会").PromptTemplateFile; got != tt.file {
+				t.Errorf("room template %q, want %q", got, tt.file)
+			}
+			if got := c.ForRoom("早会").PromptTemplateFile; got != "" {
+				t.Errorf("other room template %q, want the built-in one", got)
+			}
+		})
+	}
+}
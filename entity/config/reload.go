@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// EnvFile is the dotenv file read at startup and on every reload.
+const EnvFile = ".env"
+
+// env tracks which variables came from EnvFile, so a reload can apply
+// edits to it, including removals, without overriding the process
+// environment, which keeps precedence as it does at startup.
+var env struct {
+	mu sync.Mutex
+	// processKeys were set before EnvFile was first read.
+	processKeys map[string]bool
+	// fileKeys were last set from EnvFile.
+	fileKeys map[string]bool
+}
+
+// loadEnvFile applies EnvFile to the environment and reports whether it
+// exists.
+func loadEnvFile() (bool, error) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+
+	values, err := godotenv.Read(EnvFile)
+	found := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	for key := range env.fileKeys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+		}
+	}
+	env.fileKeys = make(map[string]bool, len(values))
+	for key, value := range values {
+		if env.processKeys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		env.fileKeys[key] = true
+	}
+	return found, nil
+}
+
+var reloadMu sync.Mutex
+
+// Reload reads EnvFile, the environment and the rooms file again. The new
+// configuration is validated and passed to prepare, which may reject it;
+// only then does it become current. On error the running configuration is
+// left untouched. Settings only read at startup keep their running value
+// and are listed in RestartPending.
+func Reload(prepare func(next *Config) error) (old, next *Config, err error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old = Current()
+	if _, err := loadEnvFile(); err != nil {
+		return old, nil, fmt.Errorf("failed to read %s: %w", EnvFile, err)
+	}
+	next, err = read()
+	if err != nil {
+		return old, nil, err
+	}
+	next.keepStartupSettings(old)
+	// A kept setting may depend on one that changed, like STT_PROVIDER on
+	// MEDIA_DIR.
+	if err := next.validate(); err != nil {
+		return old, nil, err
+	}
+	next.Version = old.Version + 1
+
+	if prepare != nil {
+		if err := prepare(next); err != nil {
+			return old, nil, err
+		}
+	}
+	current.Store(next)
+	return old, next, nil
+}
+
+// keepStartupSettings restores the settings that running components read
+// once at startup, recording the ones that changed.
+func (c *Config) keepStartupSettings(old *Config) {
+	keep := func(name string, changed bool, restore func()) {
+		if changed {
+			c.RestartPending = append(c.RestartPending, name)
+			restore()
+		}
+	}
+	keep("CHAT_PLATFORM", c.ChatPlatform != old.ChatPlatform, func() { c.ChatPlatform = old.ChatPlatform })
+	keep("BUFFER_PERSIST_DIR", c.BufferPersistDir != old.BufferPersistDir, func() { c.BufferPersistDir = old.BufferPersistDir })
+	keep("ARCHIVE_FILE", c.ArchiveFile != old.ArchiveFile, func() { c.ArchiveFile = old.ArchiveFile })
+	keep("CONCURRENT_SUMMARY", c.SummaryQueueSize != old.SummaryQueueSize, func() { c.SummaryQueueSize = old.SummaryQueueSize })
+	keep("SUMMARY_WORKERS", c.SummaryWorkers != old.SummaryWorkers, func() { c.SummaryWorkers = old.SummaryWorkers })
+	keep("ADMIN_ADDR", c.AdminAddr != old.AdminAddr, func() { c.AdminAddr = old.AdminAddr })
+	keep("ADMIN_TOKEN", c.AdminToken != old.AdminToken, func() { c.AdminToken = old.AdminToken })
+	keep("METRICS_ADDR", c.MetricsAddr != old.MetricsAddr, func() { c.MetricsAddr = old.MetricsAddr })
+	keep("LOG_FORMAT", c.LogFormat != old.LogFormat, func() { c.LogFormat = old.LogFormat })
+	keep("CONFIG_WATCH", c.ConfigWatch != old.ConfigWatch, func() { c.ConfigWatch = old.ConfigWatch })
+	sttChanged := c.STTProvider != old.STTProvider || c.STTBaseURL != old.STTBaseURL || c.STTAPIKey != old.STTAPIKey ||
+		c.STTModel != old.STTModel || c.STTLanguage != old.STTLanguage || c.STTTimeout != old.STTTimeout
+	keep("STT_*", sttChanged, func() {
+		c.STTProvider, c.STTBaseURL, c.STTAPIKey = old.STTProvider, old.STTBaseURL, old.STTAPIKey
+		c.STTModel, c.STTLanguage, c.STTTimeout = old.STTModel, old.STTLanguage, old.STTTimeout
+	})
+}
+
+// Files returns the files a reload reads: EnvFile and the rooms file.
+func (c *Config) Files() []string {
+	files := []string{EnvFile}
+	if c.RoomsConfigFile != "" {
+		files = append(files, c.RoomsConfigFile)
+	}
+	return files
+}
+
+// LLMChanged reports whether next talks to different LLM endpoints than c.
+func (c *Config) LLMChanged(next *Config) bool {
+	return c.LLMBaseURL != next.LLMBaseURL || c.LLMAPIKey != next.LLMAPIKey ||
+		!slices.Equal(c.LLMFallbacks, next.LLMFallbacks)
+}
+
+// Changes names the top-level settings that differ between c and next,
+// for logging; room overrides are reported as ROOMS_CONFIG_FILE.
+func (c *Config) Changes(next *Config) []string {
+	var changed []string
+	add := func(name string, differs bool) {
+		if differs {
+			changed = append(changed, name)
+		}
+	}
+	add("LLM_MODEL", c.LLMModel != next.LLMModel)
+	add("LLM_BASE_URL/API_KEY/FALLBACKS", c.LLMChanged(next))
+	add("SYSTEM_PROMPT_FILE", c.SystemPromptFile != next.SystemPromptFile)
+	add("PROMPT_TEMPLATE_FILE", c.PromptTemplateFile != next.PromptTemplateFile)
+	add("TARGET_ROOMS", !slices.Equal(c.TargetRooms, next.TargetRooms))
+	add("SUMMARY_TRIGGERS", c.SummaryTrigger != next.SummaryTrigger)
+	add("MAX_BUFFER_SIZE", c.MaxBufferSize != next.MaxBufferSize)
+	add("DELIVER_TO", !slices.Equal(c.DeliverTo, next.DeliverTo))
+	add("LOG_LEVEL", c.LogLevel != next.LogLevel || c.LogLevels != next.LogLevels)
+	add("SCHEDULE_TIMEZONE", c.ScheduleLocation.String() != next.ScheduleLocation.String())
+	add("DIGEST_*", c.DigestSchedule != next.DigestSchedule || c.DigestPeriod != next.DigestPeriod ||
+		!slices.Equal(c.DigestDeliverTo, next.DigestDeliverTo))
+	add("ROOMS_CONFIG_FILE", c.RoomsConfigFile != next.RoomsConfigFile || !reflect.DeepEqual(c.RoomOverrides, next.RoomOverrides))
+	return changed
+}
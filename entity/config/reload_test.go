@@ -0,0 +1,160 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+// startReload makes the configuration read from the minimal valid
+// environment with env current, as Load does at startup.
+func startReload(t *testing.T, env map[string]string) *Config {
+	t.Helper()
+	c, err := readEnv(t, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Version = 1
+	old := Current()
+	Set(c)
+	t.Cleanup(func() { Set(old) })
+	return c
+}
+
+func TestReloadKeepsStartupSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		// env is applied before the reload.
+		env         map[string]string
+		wantPending []string
+		check       func(t *testing.T, old, next *Config)
+	}{
+		{
+			name: "live setting applied",
+			env:  map[string]string{"MIN_MESSAGES_FOR_SUMMARY": "9"},
+			check: func(t *testing.T, old, next *Config) {
+				if next.SummaryTrigger.MinMessagesForSummary != 9 {
+					t.Errorf("min messages %d, want 9", next.SummaryTrigger.MinMessagesForSummary)
+				}
+				if changes := old.Changes(next); !slices.Equal(changes, []string{"SUMMARY_TRIGGERS"}) {
+					t.Errorf("changes %q", changes)
+				}
+			},
+		},
+		{
+			name:        "startup settings kept",
+			env:         map[string]string{"CHAT_PLATFORM": "fake", "SUMMARY_WORKERS": "8", "ARCHIVE_FILE": "other.db"},
+			wantPending: []string{"CHAT_PLATFORM", "ARCHIVE_FILE", "SUMMARY_WORKERS"},
+			check: func(t *testing.T, old, next *Config) {
+				if next.ChatPlatform != old.ChatPlatform || next.SummaryWorkers != old.SummaryWorkers || next.ArchiveFile != old.ArchiveFile {
+					t.Errorf("startup settings changed: %q %d %q", next.ChatPlatform, next.SummaryWorkers, next.ArchiveFile)
+				}
+			},
+		},
+		{
+			name:        "stt settings kept together",
+			env:         map[string]string{"STT_MODEL": "whisper-large", "STT_LANGUAGE": "en"},
+			wantPending: []string{"STT_*"},
+			check: func(t *testing.T, old, next *Config) {
+				if next.STTModel != old.STTModel || next.STTLanguage != old.STTLanguage {
+					t.Errorf("stt settings changed: %q %q", next.STTModel, next.STTLanguage)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			startReload(t, map[string]string{"CHAT_PLATFORM": "wechat", "SUMMARY_WORKERS": "2", "ARCHIVE_FILE": ""})
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			old, next, err := Reload(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if Current() != next || next.Version != old.Version+1 {
+				t.Errorf("version %d is not current after %d", next.Version, old.Version)
+			}
+			if !slices.Equal(next.RestartPending, tt.wantPending) {
+				t.Errorf("restart pending %q, want %q", next.RestartPending, tt.wantPending)
+			}
+			tt.check(t, old, next)
+		})
+	}
+}
+
+func TestReloadRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		prepare func(next *Config) error
+	}{
+		{name: "invalid", env: map[string]string{"SUMMARY_WORKERS": "0"}},
+		{
+			name:    "rejected by prepare",
+			env:     map[string]string{"MIN_MESSAGES_FOR_SUMMARY": "9"},
+			prepare: func(*Config) error { return errors.New("missing prompt") },
+		},
+		// STT_PROVIDER is kept from startup, so the kept value is checked
+		// against the new MEDIA_DIR.
+		{name: "kept setting invalidated", env: map[string]string{"MEDIA_DIR": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running := startReload(t, map[string]string{"STT_PROVIDER": "fake", "MEDIA_DIR": "media"})
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			if _, _, err := Reload(tt.prepare); err == nil {
+				t.Fatal("Reload succeeded")
+			}
+			if Current() != running {
+				t.Error("the running configuration was replaced")
+			}
+		})
+	}
+}
+
+func TestReloadEnvFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	startReload(t, nil)
+	t.Setenv("SUMMARY_MESSAGE_COUNT", "40")
+	env.mu.Lock()
+	env.processKeys = map[string]bool{"SUMMARY_MESSAGE_COUNT": true}
+	env.mu.Unlock()
+	t.Cleanup(func() {
+		env.mu.Lock()
+		defer env.mu.Unlock()
+		for key := range env.fileKeys {
+			os.Unsetenv(key)
+		}
+		env.processKeys, env.fileKeys = nil, nil
+	})
+
+	reload := func(content string) *Config {
+		t.Helper()
+		if err := os.WriteFile(EnvFile, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, next, err := Reload(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return next
+	}
+
+	// The process environment keeps precedence over the file.
+	c := reload("MIN_MESSAGES_FOR_SUMMARY=9\nSUMMARY_MESSAGE_COUNT=10\n")
+	if c.SummaryTrigger.MinMessagesForSummary != 9 || c.SummaryTrigger.MessageCount != 40 {
+		t.Errorf("trigger %+v after adding lines", c.SummaryTrigger)
+	}
+
+	// A removed line falls back to the default.
+	c = reload("SUMMARY_MESSAGE_COUNT=10\n")
+	if c.SummaryTrigger.MinMessagesForSummary != 5 || c.SummaryTrigger.MessageCount != 40 {
+		t.Errorf("trigger %+v after removing a line", c.SummaryTrigger)
+	}
+}
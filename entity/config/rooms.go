@@ -0,0 +1,278 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// RoomOverride replaces global settings for rooms whose name matches Match.
+// Unset fields fall back to the global value.
+type RoomOverride struct {
+	Match                 string  `yaml:"match"`
+	IntervalMinutes       *int    `yaml:"interval_minutes"`
+	MessageCount          *int    `yaml:"message_count"`
+	Keyword               *string `yaml:"keyword"`
+	MinMessagesForSummary *int    `yaml:"min_messages"`
+	MaxBufferSize         *int    `yaml:"buffer_size"`
+	Schedule              *string `yaml:"schedule"`
+	SystemPromptFile      string  `yaml:"system_prompt_file"`
+	PromptTemplateFile    string  `yaml:"prompt_template_file"`
+	Language              string  `yaml:"language"`
+	LLMModel              string  `yaml:"llm_model"`
+	Vision                *bool   `yaml:"vision"`
+	DeliverTo             Targets `yaml:"deliver_to"`
+}
+
+// Targets lists delivery sinks: "self", "room", "group:<name>",
+// "friend:<name>", "file:<dir>" or "webhook:<url>". In YAML it may be a
+// single string or a list.
+type Targets []string
+
+func (t *Targets) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = Targets{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// String lists the targets for logging, with webhook URLs redacted.
+func (t Targets) String() string {
+	redacted := make([]string, len(t))
+	for i, target := range t {
+		if kind, arg, ok := strings.Cut(target, ":"); ok && kind == "webhook" {
+			target = kind + ":" + RedactURL(arg)
+		}
+		redacted[i] = target
+	}
+	return strings.Join(redacted, ", ")
+}
+
+// RedactURL reduces raw to its scheme and host, since webhook URLs often
+// carry a token in the path or query.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "<redacted>"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+type roomsFile struct {
+	Rooms []RoomOverride `yaml:"rooms"`
+}
+
+// RoomSettings are the effective settings for one room.
+type RoomSettings struct {
+	SummaryTrigger   SummaryTriggerConfig
+	MaxBufferSize    int
+	SystemPromptFile string
+	// PromptTemplateFile is empty for the built-in user prompt.
+	PromptTemplateFile string
+	Language           string
+	LLMModel           string
+	Vision             bool
+	DeliverTo          Targets
+}
+
+// MatchRoom reports whether roomName contains pattern, ignoring case.
+func MatchRoom(roomName, pattern string) bool {
+	return strings.Contains(strings.ToLower(roomName), strings.ToLower(pattern))
+}
+
+func loadRoomOverrides(path string) ([]RoomOverride, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rooms config: %w", err)
+	}
+
+	var file roomsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rooms config %s: %w", path, err)
+	}
+	return file.Rooms, nil
+}
+
+func (o RoomOverride) validate() error {
+	if strings.TrimSpace(o.Match) == "" {
+		return fmt.Errorf("match is required")
+	}
+	for name, v := range map[string]*int{
+		"interval_minutes": o.IntervalMinutes,
+		"message_count":    o.MessageCount,
+		"min_messages":     o.MinMessagesForSummary,
+	} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if o.MaxBufferSize != nil && *o.MaxBufferSize <= 0 {
+		return fmt.Errorf("buffer_size must be positive")
+	}
+	if o.Schedule != nil && *o.Schedule != "" {
+		if _, err := ParseSchedule(*o.Schedule); err != nil {
+			return fmt.Errorf("schedule: %w", err)
+		}
+	}
+	if o.SystemPromptFile != "" {
+		if _, err := os.Stat(o.SystemPromptFile); err != nil {
+			return fmt.Errorf("system_prompt_file: %w", err)
+		}
+	}
+	if o.PromptTemplateFile != "" {
+		if _, err := os.Stat(o.PromptTemplateFile); err != nil {
+			return fmt.Errorf("prompt_template_file: %w", err)
+		}
+	}
+	return o.DeliverTo.validate()
+}
+
+func (t Targets) validate() error {
+	for _, target := range t {
+		if target == "self" || target == "room" {
+			continue
+		}
+		kind, arg, ok := strings.Cut(target, ":")
+		if ok && arg != "" {
+			switch kind {
+			case "group", "friend", "file", "webhook":
+				continue
+			}
+		}
+		return fmt.Errorf("delivery target must be self, room, group:<name>, friend:<name>, file:<dir> or webhook:<url>, got '%s'", target)
+	}
+	return nil
+}
+
+// ForRoom resolves the settings for roomName. The first override whose match
+// is contained in the room name wins, the same rule used for TARGET_ROOMS.
+func (c *Config) ForRoom(roomName string) RoomSettings {
+	settings := RoomSettings{
+		SummaryTrigger:     c.SummaryTrigger,
+		MaxBufferSize:      c.MaxBufferSize,
+		SystemPromptFile:   c.SystemPromptFile,
+		PromptTemplateFile: c.PromptTemplateFile,
+		Language:           c.SummaryLanguage,
+		LLMModel:           c.LLMModel,
+		Vision:             c.LLMVision,
+		DeliverTo:          c.DeliverTo,
+	}
+
+	for _, o := range c.RoomOverrides {
+		if !MatchRoom(roomName, o.Match) {
+			continue
+		}
+		if o.IntervalMinutes != nil {
+			settings.SummaryTrigger.IntervalMinutes = *o.IntervalMinutes
+		}
+		if o.MessageCount != nil {
+			settings.SummaryTrigger.MessageCount = *o.MessageCount
+		}
+		if o.Keyword != nil {
+			settings.SummaryTrigger.Keyword = *o.Keyword
+		}
+		if o.MinMessagesForSummary != nil {
+			settings.SummaryTrigger.MinMessagesForSummary = *o.MinMessagesForSummary
+		}
+		if o.MaxBufferSize != nil {
+			settings.MaxBufferSize = *o.MaxBufferSize
+		}
+		if o.Schedule != nil {
+			settings.SummaryTrigger.Schedule = *o.Schedule
+		}
+		if o.SystemPromptFile != "" {
+			settings.SystemPromptFile = o.SystemPromptFile
+		}
+		if o.PromptTemplateFile != "" {
+			settings.PromptTemplateFile = o.PromptTemplateFile
+		}
+		if o.Language != "" {
+			settings.Language = o.Language
+		}
+		if o.LLMModel != "" {
+			settings.LLMModel = o.LLMModel
+		}
+		if o.Vision != nil {
+			settings.Vision = *o.Vision
+		}
+		if len(o.DeliverTo) > 0 {
+			settings.DeliverTo = o.DeliverTo
+		}
+		break
+	}
+	return settings
+}
+
+// IntervalMinutes returns every positive time-trigger interval, global and per room.
+func (c *Config) IntervalMinutes() []int {
+	var intervals []int
+	if c.SummaryTrigger.IntervalMinutes > 0 {
+		intervals = append(intervals, c.SummaryTrigger.IntervalMinutes)
+	}
+	for _, o := range c.RoomOverrides {
+		if o.IntervalMinutes != nil && *o.IntervalMinutes > 0 {
+			intervals = append(intervals, *o.IntervalMinutes)
+		}
+	}
+	return intervals
+}
+
+// Schedules returns every distinct cron schedule, global and per room.
+func (c *Config) Schedules() []string {
+	var schedules []string
+	if c.SummaryTrigger.Schedule != "" {
+		schedules = append(schedules, c.SummaryTrigger.Schedule)
+	}
+	for _, o := range c.RoomOverrides {
+		if o.Schedule != nil && *o.Schedule != "" && !slices.Contains(schedules, *o.Schedule) {
+			schedules = append(schedules, *o.Schedule)
+		}
+	}
+	return schedules
+}
+
+// ParseSchedule parses a standard five-field cron expression, a descriptor
+// such as "@daily", optionally prefixed with "CRON_TZ=<zone> ". Expressions
+// without a zone use SCHEDULE_TIMEZONE.
+func ParseSchedule(spec string) (cron.Schedule, error) {
+	return cron.ParseStandard(spec)
+}
+
+// SystemPromptFiles returns the distinct prompt files referenced by the configuration.
+func (c *Config) SystemPromptFiles() []string {
+	files := []string{c.SystemPromptFile}
+	seen := map[string]bool{c.SystemPromptFile: true}
+	for _, o := range c.RoomOverrides {
+		if o.SystemPromptFile != "" && !seen[o.SystemPromptFile] {
+			seen[o.SystemPromptFile] = true
+			files = append(files, o.SystemPromptFile)
+		}
+	}
+	return files
+}
+
+// PromptTemplateFiles returns the distinct user prompt templates referenced
+// by the configuration.
+func (c *Config) PromptTemplateFiles() []string {
+	var files []string
+	if c.PromptTemplateFile != "" {
+		files = append(files, c.PromptTemplateFile)
+	}
+	for _, o := range c.RoomOverrides {
+		if o.PromptTemplateFile != "" && !slices.Contains(files, o.PromptTemplateFile) {
+			files = append(files, o.PromptTemplateFile)
+		}
+	}
+	return files
+}
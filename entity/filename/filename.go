@@ -0,0 +1,42 @@
+// Package filename turns room names into file and directory names.
+package filename
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// MaxLen keeps names well below the 255-byte limit of common file systems,
+// leaving room for extensions and temporary suffixes.
+const MaxLen = 128
+
+// hashLen is the length of the "-<hex>" suffix of shortened names.
+const hashLen = 17
+
+// For returns name escaped for use as a single path element. Names whose
+// escaped form is longer than MaxLen, such as long Chinese group names at
+// nine bytes per character, are truncated and suffixed with a hash of the
+// full name so that they stay distinct.
+func For(name string) string {
+	escaped := url.PathEscape(name)
+	if escaped == "." || escaped == ".." {
+		escaped = strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	if len(escaped) <= MaxLen {
+		return escaped
+	}
+
+	// Cut between characters, not inside one or its escape sequence.
+	var prefix strings.Builder
+	for _, r := range name {
+		part := url.PathEscape(string(r))
+		if prefix.Len()+len(part) > MaxLen-hashLen {
+			break
+		}
+		prefix.WriteString(part)
+	}
+	sum := sha256.Sum256([]byte(name))
+	return prefix.String() + "-" + hex.EncodeToString(sum[:(hashLen-1)/2])
+}
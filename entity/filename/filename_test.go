@@ -0,0 +1,49 @@
+package filename
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "weekly", want: "weekly"},
+		{name: "产品周会", want: "%E4%BA%A7%E5%93%81%E5%91%A8%E4%BC%9A"},
+		{name: "a/b", want: "a%2Fb"},
+		{name: ".", want: "%2E"},
+		{name: "..", want: "%2E%2E"},
+	}
+	for _, tt := range tests {
+		if got := For(tt.name); got != tt.want {
+			t.Errorf("For(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestForLongNames(t *testing.T) {
+	long := strings.Repeat("项目组", 20)
+	names := []string{long + "一", long + "二", strings.Repeat("x", 200)}
+	seen := map[string]string{}
+	for _, name := range names {
+		got := For(name)
+		if len(got) > MaxLen {
+			t.Errorf("For(%q) is %d bytes, want at most %d", name, len(got), MaxLen)
+		}
+		// The prefix must still be a valid escape of whole characters.
+		prefix := got[:len(got)-hashLen]
+		if _, err := url.PathUnescape(prefix); err != nil {
+			t.Errorf("For(%q) cuts an escape sequence: %q", name, prefix)
+		}
+		if other, ok := seen[got]; ok {
+			t.Errorf("%q and %q share the file name %q", name, other, got)
+		}
+		seen[got] = name
+		if For(name) != got {
+			t.Errorf("For(%q) is not stable", name)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package filewatch reports changes to individual files in a way that
+// survives editors and ConfigMap mounts replacing them.
+package filewatch
+
+import (
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Debounce is how long a Watcher waits after the last event for a file
+// before reporting it, so an editor's truncate-write-rename sequence is
+// read once, complete.
+const Debounce = 300 * time.Millisecond
+
+// configMapData is the symlink Kubernetes swaps to update a mounted
+// ConfigMap; the files themselves never see an event.
+const configMapData = "..data"
+
+// Watcher watches the parent directories of its files rather than the
+// files, because replacing a file (rename over it, remove and recreate)
+// drops a watch on the file itself. Create, Write, Rename and Remove events
+// all count as a change.
+type Watcher struct {
+	fs       *fsnotify.Watcher
+	onChange func(paths []string)
+	logger   *slog.Logger
+
+	mu sync.Mutex
+	// files maps each watched file to its directory.
+	files map[string]string
+	dirs  map[string]bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New starts a watcher that calls onChange, from its own goroutine, with
+// the files that changed once events have been quiet for Debounce.
+func New(logger *slog.Logger, onChange func(paths []string)) (*Watcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		fs:       fs,
+		onChange: onChange,
+		logger:   logger,
+		files:    make(map[string]string),
+		dirs:     make(map[string]bool),
+		stop:     make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Add watches path. Adding a file twice is a no-op; the file need not exist.
+func (w *Watcher) Add(path string) error {
+	path = filepath.Clean(path)
+	dir := filepath.Dir(path)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.files[path]; ok {
+		return nil
+	}
+	if !w.dirs[dir] {
+		if err := w.fs.Add(dir); err != nil {
+			return err
+		}
+		w.dirs[dir] = true
+	}
+	w.files[path] = dir
+	return nil
+}
+
+// Files returns the watched files.
+func (w *Watcher) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	files := make([]string, 0, len(w.files))
+	for path := range w.files {
+		files = append(files, path)
+	}
+	return files
+}
+
+func (w *Watcher) Close() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		w.fs.Close()
+	})
+}
+
+func (w *Watcher) run() {
+	pending := make(map[string]bool)
+	debounce := time.NewTimer(Debounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				w.logger.Debug("file watcher events channel closed")
+				return
+			}
+			changed := w.match(filepath.Clean(event.Name))
+			if len(changed) == 0 {
+				continue
+			}
+			w.logger.Debug("watched file event", "file", event.Name, "op", event.Op.String())
+			for _, path := range changed {
+				pending[path] = true
+			}
+			debounce.Reset(Debounce)
+		case <-debounce.C:
+			paths := make([]string, 0, len(pending))
+			for path := range pending {
+				paths = append(paths, path)
+			}
+			clear(pending)
+			w.onChange(paths)
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				w.logger.Debug("file watcher errors channel closed")
+				return
+			}
+			w.logger.Error("file watcher error", "err", err)
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// match returns the watched files affected by an event on name.
+func (w *Watcher) match(name string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.files[name]; ok {
+		return []string{name}
+	}
+	if filepath.Base(name) != configMapData {
+		return nil
+	}
+	var inDir []string
+	for path, dir := range w.files {
+		if dir == filepath.Dir(name) {
+			inDir = append(inDir, path)
+		}
+	}
+	return inDir
+}
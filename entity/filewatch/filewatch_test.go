@@ -0,0 +1,163 @@
+package filewatch
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// startWatcher watches files and returns the batches of changed files it
+// reports.
+func startWatcher(t *testing.T, files ...string) (*Watcher, <-chan []string) {
+	t.Helper()
+	changes := make(chan []string, 10)
+	w, err := New(slog.Default(), func(paths []string) {
+		slices.Sort(paths)
+		changes <- paths
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(w.Close)
+	for _, file := range files {
+		if err := w.Add(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w, changes
+}
+
+// expectChange waits for one batch equal to want and checks that no other
+// batch follows it.
+func expectChange(t *testing.T, changes <-chan []string, want ...string) {
+	t.Helper()
+	select {
+	case got := <-changes:
+		if !slices.Equal(got, want) {
+			t.Fatalf("changed %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change reported, want %q", want)
+	}
+	expectNoChange(t, changes)
+}
+
+func expectNoChange(t *testing.T, changes <-chan []string) {
+	t.Helper()
+	select {
+	case got := <-changes:
+		t.Fatalf("unexpected change %q", got)
+	case <-time.After(2 * Debounce):
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherDebounces(t *testing.T) {
+	dir := t.TempDir()
+	env := filepath.Join(dir, ".env")
+	writeFile(t, env, "A=1")
+	_, changes := startWatcher(t, env)
+
+	for i := range 5 {
+		writeFile(t, env, "A="+strconv.Itoa(i))
+		time.Sleep(Debounce / 10)
+	}
+	expectChange(t, changes, env)
+}
+
+func TestWatcherReplacedFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace func(t *testing.T, path string)
+	}{
+		{
+			name: "rename over",
+			replace: func(t *testing.T, path string) {
+				tmp := path + ".swp"
+				writeFile(t, tmp, "A=2")
+				if err := os.Rename(tmp, path); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "remove and recreate",
+			replace: func(t *testing.T, path string) {
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+				writeFile(t, path, "A=2")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			env := filepath.Join(dir, ".env")
+			writeFile(t, env, "A=1")
+			_, changes := startWatcher(t, env)
+
+			tt.replace(t, env)
+			expectChange(t, changes, env)
+
+			// The watch survives the replacement.
+			writeFile(t, env, "A=3")
+			expectChange(t, changes, env)
+		})
+	}
+}
+
+func TestWatcherIgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	env := filepath.Join(dir, ".env")
+	_, changes := startWatcher(t, env)
+
+	writeFile(t, filepath.Join(dir, "notes.txt"), "x")
+	expectNoChange(t, changes)
+
+	// A watched file may be created after Add.
+	writeFile(t, env, "A=1")
+	expectChange(t, changes, env)
+}
+
+func TestWatcherConfigMapSwap(t *testing.T) {
+	dir := t.TempDir()
+	env, rooms := filepath.Join(dir, ".env"), filepath.Join(dir, "rooms.yaml")
+	writeFile(t, env, "A=1")
+	writeFile(t, rooms, "rooms: []")
+	_, changes := startWatcher(t, env, rooms)
+
+	// Kubernetes points ..data at a new directory and renames it into place.
+	if err := os.Mkdir(filepath.Join(dir, "..v2"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..v2", filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, configMapData)); err != nil {
+		t.Fatal(err)
+	}
+	expectChange(t, changes, env, rooms)
+}
+
+func TestWatcherAddTwice(t *testing.T) {
+	dir := t.TempDir()
+	env := filepath.Join(dir, ".env")
+	w, _ := startWatcher(t, env, filepath.Join(dir, ".", ".env"))
+	if files := w.Files(); !slices.Equal(files, []string{env}) {
+		t.Errorf("Files() = %q, want %q", files, []string{env})
+	}
+	if err := w.Add(filepath.Join(dir, "missing", "rooms.yaml")); err == nil {
+		t.Error("Add succeeded for a file in a missing directory")
+	}
+}
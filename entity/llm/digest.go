@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+)
+
+// digestSystemPrompt replaces the minutes prompt for the cross-room pass:
+// the input is already minutes, and the reader is someone who was in none
+// of the rooms.
+const digestSystemPrompt = `你是一名负责向管理层汇报的助理。你会收到同一时间段内多个群聊各自的会议纪要，请将它们整合为一份简洁的跨群摘要，使用 Markdown，按以下结构输出：
+
+## 🔥 重点话题
+按重要性列出最多 5 个跨群的主要话题，每条注明涉及的群组。
+
+## ✅ 关键决策
+列出已经做出的决策，注明群组。
+
+## 📌 未完成待办
+列出尚未完成的待办事项，注明群组、负责人和截止时间（如有）。
+
+## ⚠️ 风险与待解决问题
+列出需要关注的风险、阻塞和悬而未决的问题。
+
+要求：合并不同群组中的重复内容，不要编造纪要中没有的信息；某一部分没有内容时写"无"。`
+
+// GenerateDigest merges the minutes of several rooms into one digest.
+// Each section holds one room's minutes, already labelled with the room;
+// period names the time span, e.g. "5月1日". Sections that do not fit one
+// request are digested in groups and the group digests merged in turn.
+func (s *Service) GenerateDigest(ctx context.Context, p Profile, period string, sections []string) (string, string, error) {
+	if len(sections) == 0 {
+		return "", "", fmt.Errorf("no summaries to digest")
+	}
+	var models modelSet
+	merge := func(group []string) (string, error) {
+		digest, model, err := s.digest(ctx, p, period, group)
+		models.add(model)
+		return digest, err
+	}
+
+	budget := config.Current().SummaryChunkTokens
+	var digest string
+	var err error
+	if len(SplitByTokens(sections, budget)) == 1 {
+		digest, err = merge(sections)
+	} else {
+		// The last round always merges, so the result is a digest.
+		digest, err = s.reduce(sections, budget, merge)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return digest, models.String(), nil
+}
+
+func (s *Service) digest(ctx context.Context, p Profile, period string, sections []string) (string, string, error) {
+	userPrompt := fmt.Sprintf("以下是%s各群组的会议纪要，请生成跨群摘要：\n\n%s",
+		period, strings.Join(sections, "\n\n---\n\n"))
+	return s.completeWith(ctx, p, digestSystemPrompt, userPrompt, nil,
+		openai.ChatCompletionNewParamsResponseFormatUnion{})
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	openai "github.com/openai/openai-go/v3"
+)
+
+// Image is a picture sent along with the transcript to a vision model.
+type Image struct {
+	// Line is the index of the transcript line the image belongs to.
+	Line int
+	// Caption introduces the image, usually with its transcript line.
+	Caption string
+	// URL is an http(s) URL or a base64 data URL.
+	URL string
+}
+
+// LoadImage reads an image file into a data URL, refusing files larger
+// than maxBytes or that are not images.
+func LoadImage(path string, maxBytes int64) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxBytes {
+		return "", fmt.Errorf("%s is %d bytes, over the %d byte limit", path, info.Size(), maxBytes)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("%s is not an image (%s)", path, mimeType)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// imagesIn returns the images attached to lines [start, end).
+func imagesIn(images []Image, start, end int) []Image {
+	var in []Image
+	for _, img := range images {
+		if img.Line >= start && img.Line < end {
+			in = append(in, img)
+		}
+	}
+	return in
+}
+
+// userMessage builds the user turn, as plain text or as text followed by
+// captioned image parts.
+func userMessage(prompt string, images []Image) openai.ChatCompletionMessageParamUnion {
+	if len(images) == 0 {
+		return openai.UserMessage(prompt)
+	}
+
+	parts := []openai.ChatCompletionContentPartUnionParam{openai.TextContentPart(prompt)}
+	for _, img := range images {
+		parts = append(parts,
+			openai.TextContentPart(img.Caption),
+			openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: img.URL}),
+		)
+	}
+	return openai.UserMessage(parts)
+}
@@ -0,0 +1,170 @@
+// Package llmtest provides a local OpenAI-compatible chat completions
+// server for tests.
+package llmtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Request is the part of a chat completion request a test looks at.
+type Request struct {
+	Model  string
+	System string
+	User   string
+	// Images counts the image parts of the user message.
+	Images int
+	// Format is the response_format type, empty for plain text.
+	Format string
+}
+
+// Server answers every chat completion with its reply, echoing the
+// requested model, unless a failure is queued.
+type Server struct {
+	// URL is the base URL to configure as LLM_BASE_URL.
+	URL string
+
+	mu       sync.Mutex
+	reply    string
+	status   int
+	failures []int
+	header   http.Header
+	requests []Request
+}
+
+// NewServer starts a server replying with reply and stops it when the
+// test ends.
+func NewServer(t testing.TB, reply string) *Server {
+	t.Helper()
+	s := &Server{reply: reply, header: http.Header{}}
+	srv := httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(srv.Close)
+	s.URL = srv.URL + "/"
+	return s
+}
+
+// SetReply changes the content of later replies.
+func (s *Server) SetReply(reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reply = reply
+}
+
+// Fail makes every later request fail with status; 0 restores replies.
+func (s *Server) Fail(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// FailNext makes the next requests fail with statuses, one each, before
+// replies resume.
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// SetErrorHeader sets a header of failed responses, such as Retry-After.
+func (s *Server) SetErrorHeader(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.header.Set(key, value)
+}
+
+// Requests returns the requests received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		ResponseFormat struct {
+			Type string `json:"type"`
+		} `json:"response_format"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := Request{Model: body.Model, Format: body.ResponseFormat.Type}
+	for _, msg := range body.Messages {
+		text, images := content(msg.Content)
+		switch msg.Role {
+		case "system":
+			req.System = text
+		case "user":
+			req.User, req.Images = text, images
+		}
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	status := s.status
+	if len(s.failures) > 0 {
+		status, s.failures = s.failures[0], s.failures[1:]
+	}
+	reply := s.reply
+	header := s.header.Clone()
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if status != 0 && status != http.StatusOK {
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error": map[string]any{"message": http.StatusText(status), "type": "test_error"},
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":      "test",
+		"object":  "chat.completion",
+		"created": 0,
+		"model":   body.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"finish_reason": "stop",
+			"message":       map[string]any{"role": "assistant", "content": reply},
+		}},
+		"usage": map[string]any{"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2},
+	})
+}
+
+// content returns the text of a message, which is either a string or a
+// list of parts, and its number of image parts.
+func content(raw json.RawMessage) (string, int) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, 0
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	json.Unmarshal(raw, &parts)
+	var texts []string
+	images := 0
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			texts = append(texts, part.Text)
+		case "image_url":
+			images++
+		}
+	}
+	return strings.Join(texts, "\n"), images
+}
@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/shared"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
+)
+
+// provider is one OpenAI-compatible endpoint of the fallback chain.
+type provider struct {
+	client openai.Client
+	// model is empty for the primary provider, whose model comes from the
+	// request profile.
+	model shared.ChatModel
+}
+
+func newProvider(baseURL, apiKey string, model shared.ChatModel) provider {
+	return provider{
+		client: openai.NewClient(
+			option.WithAPIKey(apiKey),
+			option.WithBaseURL(baseURL),
+			// Retries are handled by chat so they can move on to fallbacks.
+			option.WithMaxRetries(0),
+		),
+		model: model,
+	}
+}
+
+func newProviders() []provider {
+	cfg := config.Current()
+	providers := []provider{newProvider(cfg.LLMBaseURL, cfg.LLMAPIKey, "")}
+	for _, fb := range cfg.LLMFallbacks {
+		providers = append(providers, newProvider(fb.BaseURL, fb.APIKey, shared.ChatModel(fb.Model)))
+	}
+	return providers
+}
+
+// chat sends params to the primary provider and then to each fallback in
+// order, retrying retryable errors on each of them before moving on. It
+// returns the model that answered.
+func (s *Service) chat(ctx context.Context, p Profile, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, shared.ChatModel, error) {
+	providers := *s.providers.Load()
+	var errs []error
+	for i, prov := range providers {
+		params.Model = prov.model
+		if i == 0 {
+			params.Model = s.modelFor(p)
+		}
+
+		resp, err := s.chatWithRetries(ctx, prov, params)
+		if err == nil {
+			if i > 0 {
+				metrics.LLMFallbacks.WithLabelValues(string(params.Model)).Inc()
+				s.logger.Warn("request answered by fallback provider", "model", params.Model, "fallback", i)
+			}
+			return resp, params.Model, nil
+		}
+		if ctx.Err() != nil {
+			return nil, params.Model, err
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", params.Model, err))
+		if i+1 < len(providers) {
+			s.logger.Warn("provider failed, trying fallback", "model", params.Model, "err", err)
+		}
+	}
+	return nil, "", errors.Join(errs...)
+}
+
+func (s *Service) chatWithRetries(ctx context.Context, prov provider, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	model := string(params.Model)
+	for attempt := 0; ; attempt++ {
+		s.logger.Debug("sending request", "model", model, "attempt", attempt+1)
+
+		attemptCtx, cancel := context.WithTimeout(ctx, config.Current().LLMTimeout)
+		start := time.Now()
+		resp, err := prov.client.Chat.Completions.New(attemptCtx, params)
+		cancel()
+
+		if err == nil {
+			metrics.LLMRequestDuration.WithLabelValues(model, "success").Observe(time.Since(start).Seconds())
+			return resp, nil
+		}
+		metrics.LLMRequestDuration.WithLabelValues(model, "error").Observe(time.Since(start).Seconds())
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt >= config.Current().LLMMaxRetries || !isRetryable(err) {
+			return nil, err
+		}
+
+		delay := backoff(attempt)
+		if after, ok := retryAfter(err); ok {
+			if after > config.Current().LLMRetryMax {
+				// The provider asks for a longer pause than we are willing
+				// to wait; let the next provider take the request instead.
+				return nil, err
+			}
+			delay = after
+		}
+
+		metrics.LLMRetries.WithLabelValues(model).Inc()
+		s.logger.Warn("request failed, retrying", "model", model, "attempt", attempt+1,
+			"duration", time.Since(start), "delay", delay, "err", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// isRetryable reports whether err is a rate limit, a server error or a
+// transport failure such as a timeout.
+func isRetryable(err error) bool {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		switch code := apiErr.StatusCode; {
+		case code == http.StatusRequestTimeout, code == http.StatusConflict, code == http.StatusTooManyRequests:
+			return true
+		default:
+			return code >= 500
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// backoff returns the exponential delay before retry attempt+1, with jitter
+// in the upper half of the interval.
+func backoff(attempt int) time.Duration {
+	cfg := config.Current()
+	delay := cfg.LLMRetryBase << min(attempt, 16)
+	if delay <= 0 || delay > cfg.LLMRetryMax {
+		delay = cfg.LLMRetryMax
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryAfter reads the Retry-After-Ms or Retry-After header of an API error.
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return 0, false
+	}
+	header := apiErr.Response.Header
+
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms >= 0 {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm/llmtest"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "rate limited", err: &openai.Error{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "request timeout", err: &openai.Error{StatusCode: http.StatusRequestTimeout}, want: true},
+		{name: "server error", err: &openai.Error{StatusCode: http.StatusBadGateway}, want: true},
+		{name: "bad request", err: &openai.Error{StatusCode: http.StatusBadRequest}, want: false},
+		{name: "unauthorized", err: &openai.Error{StatusCode: http.StatusUnauthorized}, want: false},
+		{name: "deadline", err: fmt.Errorf("post: %w", context.DeadlineExceeded), want: true},
+		{name: "network", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "other", err: errors.New("boom"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	old := config.Current()
+	config.Set(&config.Config{LLMRetryBase: 100 * time.Millisecond, LLMRetryMax: time.Second})
+	t.Cleanup(func() { config.Set(old) })
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 0, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{attempt: 2, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{attempt: 4, min: 500 * time.Millisecond, max: time.Second},
+		// A shift past the width of time.Duration must not wrap around.
+		{attempt: 100, min: 500 * time.Millisecond, max: time.Second},
+	}
+	for _, tt := range tests {
+		for range 100 {
+			if got := backoff(tt.attempt); got < tt.min || got > tt.max {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tt.attempt, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "milliseconds", header: http.Header{"Retry-After-Ms": {"250"}}, want: 250 * time.Millisecond, wantOK: true},
+		{name: "seconds", header: http.Header{"Retry-After": {"2"}}, want: 2 * time.Second, wantOK: true},
+		{
+			name:   "milliseconds win",
+			header: http.Header{"Retry-After-Ms": {"10"}, "Retry-After": {"2"}},
+			want:   10 * time.Millisecond, wantOK: true,
+		},
+		{name: "past date", header: http.Header{"Retry-After": {"Mon, 02 Jan 2006 15:04:05 GMT"}}, want: 0, wantOK: true},
+		{name: "negative", header: http.Header{"Retry-After": {"-1"}}},
+		{name: "garbage", header: http.Header{"Retry-After": {"soon"}}},
+		{name: "missing", header: http.Header{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("chat: %w", &openai.Error{StatusCode: http.StatusTooManyRequests, Response: &http.Response{Header: tt.header}})
+			got, ok := retryAfter(err)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	err := &openai.Error{Response: &http.Response{Header: http.Header{"Retry-After": {future}}}}
+	if got, ok := retryAfter(err); !ok || got < 59*time.Minute || got > time.Hour {
+		t.Errorf("retryAfter(%s) = %v, %v, want about an hour", future, got, ok)
+	}
+	if _, ok := retryAfter(errors.New("boom")); ok {
+		t.Error("retryAfter reported a delay for a non-API error")
+	}
+}
+
+func TestChatRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   []int
+		retryAfter string
+		fallback   bool
+		// wantPrimary and wantFallback count the requests each server got.
+		wantPrimary  int
+		wantFallback int
+		wantModel    string
+		wantErr      bool
+	}{
+		{name: "retried until success", failures: []int{500, 429}, wantPrimary: 3, wantModel: "test-model"},
+		{name: "retries exhausted", failures: []int{503, 503, 503}, wantPrimary: 3, wantErr: true},
+		{name: "client error not retried", failures: []int{400}, wantPrimary: 1, wantErr: true},
+		{name: "short retry-after honoured", failures: []int{429}, retryAfter: "0", wantPrimary: 2, wantModel: "test-model"},
+		{
+			name: "long retry-after moves to fallback", failures: []int{429}, retryAfter: "120", fallback: true,
+			wantPrimary: 1, wantFallback: 1, wantModel: "fallback-model",
+		},
+		{
+			name: "exhausted primary moves to fallback", failures: []int{500, 500, 500}, fallback: true,
+			wantPrimary: 3, wantFallback: 1, wantModel: "fallback-model",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, server := newTestService(t, 1000)
+			cfg := config.Current()
+			cfg.LLMMaxRetries = 2
+			cfg.LLMRetryBase = time.Millisecond
+			cfg.LLMRetryMax = 10 * time.Millisecond
+			fallback := llmtest.NewServer(t, testReply)
+			if tt.fallback {
+				cfg.LLMFallbacks = []config.LLMProvider{{BaseURL: fallback.URL, APIKey: "test", Model: "fallback-model"}}
+				providers := newProviders()
+				s.providers.Store(&providers)
+			}
+			server.FailNext(tt.failures...)
+			if tt.retryAfter != "" {
+				server.SetErrorHeader("Retry-After", tt.retryAfter)
+			}
+
+			_, model, err := s.GenerateSummary(context.Background(), Profile{}, Meeting{Room: "room"}, []string{"消息"}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if model != tt.wantModel {
+				t.Errorf("answered by %q, want %q", model, tt.wantModel)
+			}
+			if got := len(server.Requests()); got != tt.wantPrimary {
+				t.Errorf("%d primary requests, want %d", got, tt.wantPrimary)
+			}
+			if got := len(fallback.Requests()); got != tt.wantFallback {
+				t.Errorf("%d fallback requests, want %d", got, tt.wantFallback)
+			}
+		})
+	}
+}
@@ -3,138 +3,339 @@ package llm
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync/atomic"
+	"text/template"
+	"time"
 
-	"github.com/fsnotify/fsnotify"
+	"github.com/alphadose/haxmap"
 	openai "github.com/openai/openai-go/v3"
-	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/shared"
 	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/filewatch"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
 )
 
 type Service struct {
-	client       openai.Client
-	model        shared.ChatModel
-	systemPrompt atomic.Value
-	watcher      *fsnotify.Watcher
-	stopWatcher  chan struct{}
+	// providers holds the primary provider followed by the fallbacks; it
+	// is replaced when a configuration reload changes the endpoints.
+	providers     atomic.Pointer[[]provider]
+	systemPrompts *haxmap.Map[string, string]
+	// templates holds the user prompt templates by file; defaultTemplate is
+	// used by rooms without one.
+	templates       *haxmap.Map[string, *template.Template]
+	defaultTemplate *template.Template
+	watcher         *filewatch.Watcher
+	logger          *slog.Logger
 }
 
-func (s *Service) loadSystemPrompt() error {
-	systemPromptBytes, err := os.ReadFile(config.AppConfig.SystemPromptFile)
+// Profile selects the model and prompts used for a request. Empty fields
+// fall back to the global configuration.
+type Profile struct {
+	Model            string
+	SystemPromptFile string
+	// PromptTemplateFile is the user prompt template; empty uses the
+	// built-in one.
+	PromptTemplateFile string
+	// Language is the {{.Language}} of the template.
+	Language string
+	// Vision reports whether the model accepts image input.
+	Vision bool
+}
+
+func (s *Service) loadSystemPrompt(path string) error {
+	systemPromptBytes, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to read system prompt: %w", err)
 	}
 
 	prompt := strings.TrimSpace(string(systemPromptBytes))
-	s.systemPrompt.Store(prompt)
+	if prompt == "" {
+		return fmt.Errorf("system prompt %s is empty", path)
+	}
+	s.systemPrompts.Set(filepath.Clean(path), prompt)
 
-	log.Printf("[LLM] System prompt loaded (%d chars)", len(prompt))
+	s.logger.Info("system prompt loaded", "file", path, "chars", len(prompt))
 	return nil
 }
 
-func (s *Service) getSystemPrompt() string {
-	return s.systemPrompt.Load().(string)
+func (s *Service) getSystemPrompt(path string) string {
+	global := config.Current().SystemPromptFile
+	if path == "" {
+		path = global
+	}
+	if prompt, ok := s.systemPrompts.Get(filepath.Clean(path)); ok {
+		return prompt
+	}
+	prompt, _ := s.systemPrompts.Get(filepath.Clean(global))
+	return prompt
+}
+
+func (s *Service) modelFor(p Profile) shared.ChatModel {
+	if p.Model != "" {
+		return shared.ChatModel(p.Model)
+	}
+	return shared.ChatModel(config.Current().LLMModel)
 }
 
 func New() *Service {
 	s := &Service{
-		client: openai.NewClient(
-			option.WithAPIKey(config.AppConfig.LLMAPIKey),
-			option.WithBaseURL(config.AppConfig.LLMBaseURL),
-		),
-		model:       shared.ChatModel(config.AppConfig.LLMModel),
-		stopWatcher: make(chan struct{}),
+		systemPrompts: haxmap.New[string, string](),
+		templates:     haxmap.New[string, *template.Template](),
+		logger:        logging.For(logging.LLM),
 	}
+	providers := newProviders()
+	s.providers.Store(&providers)
 
-	if err := s.loadSystemPrompt(); err != nil {
-		log.Fatalf("[LLM] Failed to load initial system prompt: %v", err)
+	defaultTmpl, err := parseTemplate("default", defaultTemplate)
+	if err != nil {
+		logging.Fatal(s.logger, "invalid built-in prompt template", "err", err)
 	}
+	s.defaultTemplate = defaultTmpl
 
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		log.Fatalf("[LLM] Failed to create file watcher: %v", err)
-	}
-	s.watcher = watcher
-
-	if err := watcher.Add(config.AppConfig.SystemPromptFile); err != nil {
-		watcher.Close()
-		log.Fatalf("[LLM] Failed to watch system prompt file: %v", err)
-	}
-
-	go func() {
-		defer watcher.Close()
-		for {
-			select {
-			case event, ok := <-watcher.Events:
-				if !ok {
-					log.Println("[LLM] File watcher events channel closed")
-					return
-				}
-				if event.Has(fsnotify.Write) {
-					log.Printf("[LLM] System prompt file changed, reloading...")
-					if err := s.loadSystemPrompt(); err != nil {
-						log.Printf("[LLM] Error reloading system prompt: %v", err)
-					}
-				}
-			case err, ok := <-watcher.Errors:
-				if !ok {
-					log.Println("[LLM] File watcher errors channel closed")
-					return
-				}
-				log.Printf("[LLM] File watcher error: %v", err)
-			case <-s.stopWatcher:
-				log.Println("[LLM] File watcher stopped")
-				return
-			}
+	promptFiles := config.Current().SystemPromptFiles()
+	for _, path := range promptFiles {
+		if err := s.loadSystemPrompt(path); err != nil {
+			logging.Fatal(s.logger, "failed to load initial system prompt", "err", err)
 		}
-	}()
+	}
+	templateFiles := config.Current().PromptTemplateFiles()
+	for _, path := range templateFiles {
+		if err := s.loadTemplate(path); err != nil {
+			logging.Fatal(s.logger, "failed to load prompt template", "err", err)
+		}
+	}
 
-	log.Printf("[LLM] File watcher started for: %s", config.AppConfig.SystemPromptFile)
+	s.startWatcher(append(promptFiles, templateFiles...))
 	return s
 }
 
+// Model returns the model name used for p.
+func (s *Service) Model(p Profile) string {
+	return string(s.modelFor(p))
+}
+
 func (s *Service) Close() {
-	close(s.stopWatcher)
 	if s.watcher != nil {
 		s.watcher.Close()
+		s.logger.Info("file watcher stopped")
+	}
+}
+
+// GenerateSummary summarizes messages of meeting and returns the minutes
+// with the models that answered. images are attached to the request
+// covering their line and are ignored unless p.Vision is set.
+func (s *Service) GenerateSummary(ctx context.Context, p Profile, meeting Meeting, messages []string,
+	images []Image) (minutes, model string, err error) {
+	cfg := config.Current()
+	budget := cfg.SummaryChunkTokens
+	strategy := cfg.SummaryStrategy
+
+	total := 0
+	for _, msg := range messages {
+		total += EstimateTokens(msg) + 1
+	}
+
+	if strategy == "single" || (strategy == "auto" && total <= budget) {
+		userPrompt, err := s.userPrompt(p, meeting, "", 0, 0, messages)
+		if err != nil {
+			return "", "", err
+		}
+		return s.complete(ctx, p, userPrompt, images)
+	}
+
+	return s.mapReduce(ctx, p, meeting, messages, images, budget, total)
+}
+
+// UpdateSummary produces a cumulative summary from the previous minutes and
+// the messages received since. New messages that do not fit the budget next
+// to the previous minutes are summarized on their own first and then merged.
+func (s *Service) UpdateSummary(ctx context.Context, p Profile, meeting Meeting, previous string, messages []string,
+	images []Image) (minutes, model string, err error) {
+	if previous == "" {
+		return s.GenerateSummary(ctx, p, meeting, messages, images)
+	}
+
+	total := EstimateTokens(previous)
+	for _, msg := range messages {
+		total += EstimateTokens(msg) + 1
+	}
+
+	cfg := config.Current()
+	if cfg.SummaryStrategy == "single" || (cfg.SummaryStrategy == "auto" && total <= cfg.SummaryChunkTokens) {
+		userPrompt, err := s.userPrompt(p, meeting, previous, 0, 0, messages)
+		if err != nil {
+			return "", "", err
+		}
+		return s.complete(ctx, p, userPrompt, images)
+	}
+
+	latest, latestModel, err := s.GenerateSummary(ctx, p, meeting, messages, images)
+	if err != nil {
+		return "", "", err
 	}
+	s.logger.Info("merging new minutes into previous summary", "model", s.modelFor(p))
+	minutes, model, err = s.complete(ctx, p, reducePrompt([]string{previous, latest}), nil)
+	if err != nil {
+		return "", "", err
+	}
+	var models modelSet
+	models.add(latestModel)
+	models.add(model)
+	return minutes, models.String(), nil
 }
 
-func (s *Service) GenerateSummary(ctx context.Context, messages []string) (string, error) {
-	systemPrompt := s.getSystemPrompt()
+// mapReduce summarizes token-bounded chunks separately, then merges the
+// partial minutes until a single document remains.
+func (s *Service) mapReduce(ctx context.Context, p Profile, meeting Meeting, messages []string, images []Image,
+	budget, total int) (string, string, error) {
+	chunks := SplitByTokens(messages, budget)
+	s.logger.Info("map-reduce summarization", "tokens", total, "chunks", len(chunks), "budget", budget)
+
+	var models modelSet
+	partials := make([]string, 0, len(chunks))
+	start := 0
+	for i, chunk := range chunks {
+		userPrompt, err := s.userPrompt(p, meeting, "", i+1, len(chunks), chunk)
+		if err != nil {
+			return "", "", err
+		}
+		partial, model, err := s.complete(ctx, p, userPrompt, imagesIn(images, start, start+len(chunk)))
+		if err != nil {
+			return "", "", fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		models.add(model)
+		partials = append(partials, partial)
+		start += len(chunk)
+	}
+
+	if len(partials) == 0 {
+		return "", "", fmt.Errorf("no messages to summarize")
+	}
+	minutes, err := s.reduce(partials, budget, func(group []string) (string, error) {
+		merged, model, err := s.complete(ctx, p, reducePrompt(group), nil)
+		models.add(model)
+		return merged, err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return minutes, models.String(), nil
+}
 
-	conversationText := strings.Join(messages, "\n")
-	userPrompt := fmt.Sprintf("请为以下群聊消息生成会议纪要：\n\n%s", conversationText)
+// reduce merges items with merge until one remains, which it returns. Each
+// round merges groups of items that fit budget together; single items pass
+// through to the next round unchanged.
+func (s *Service) reduce(items []string, budget int, merge func(group []string) (string, error)) (string, error) {
+	round := 0
+	for len(items) > 1 {
+		round++
+		groups := SplitByTokens(items, budget)
+		if len(groups) == len(items) {
+			// Every item fills the budget on its own; merge pairwise so the
+			// reduction still terminates.
+			groups = pairUp(items)
+		}
 
-	log.Printf("[LLM] Sending request to %s...", s.model)
+		s.logger.Info("reduce round", "round", round, "items", len(items), "groups", len(groups), "budget", budget)
+		merged := make([]string, 0, len(groups))
+		for i, group := range groups {
+			if len(group) == 1 {
+				merged = append(merged, group[0])
+				continue
+			}
+			result, err := merge(group)
+			if err != nil {
+				return "", fmt.Errorf("reduce round %d group %d: %w", round, i+1, err)
+			}
+			merged = append(merged, result)
+		}
+		items = merged
+	}
+	return items[0], nil
+}
 
-	resp, err := s.client.Chat.Completions.New(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Model: s.model,
-			Messages: []openai.ChatCompletionMessageParamUnion{
-				openai.SystemMessage(systemPrompt),
-				openai.UserMessage(userPrompt),
-			},
+func pairUp(items []string) [][]string {
+	groups := make([][]string, 0, (len(items)+1)/2)
+	for i := 0; i < len(items); i += 2 {
+		end := min(i+2, len(items))
+		groups = append(groups, items[i:end])
+	}
+	return groups
+}
+
+// modelSet collects the models that answered the requests behind one
+// result, in order of first use. A fallback provider may answer some of
+// them.
+type modelSet []string
+
+// add records model, which may itself be a list from String.
+func (m *modelSet) add(model string) {
+	for name := range strings.SplitSeq(model, ", ") {
+		if name != "" && !slices.Contains(*m, name) {
+			*m = append(*m, name)
+		}
+	}
+}
+
+func (m modelSet) String() string {
+	return strings.Join(m, ", ")
+}
+
+func reducePrompt(partials []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "以下是同一段群聊按时间顺序分段生成的 %d 份会议纪要，请将它们合并为一份完整的会议纪要，"+
+		"去除重复内容，保留全部决策和待办事项，并保持原有格式：", len(partials))
+	for i, partial := range partials {
+		fmt.Fprintf(&sb, "\n\n### 第 %d 部分\n%s", i+1, partial)
+	}
+	return sb.String()
+}
+
+// complete sends one request and returns the reply with the model that
+// answered it.
+func (s *Service) complete(ctx context.Context, p Profile, userPrompt string, images []Image) (string, string, error) {
+	return s.completeWith(ctx, p, s.getSystemPrompt(p.SystemPromptFile), userPrompt, images,
+		openai.ChatCompletionNewParamsResponseFormatUnion{})
+}
+
+func (s *Service) completeWith(ctx context.Context, p Profile, systemPrompt, userPrompt string, images []Image,
+	format openai.ChatCompletionNewParamsResponseFormatUnion) (string, string, error) {
+	if !p.Vision {
+		images = nil
+	}
+
+	start := time.Now()
+	resp, model, err := s.chat(ctx, p, openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(systemPrompt),
+			userMessage(userPrompt, images),
 		},
-	)
+		ResponseFormat: format,
+	})
 
 	if err != nil {
-		log.Printf("[LLM] Error: %v", err)
-		return "", fmt.Errorf("LLM service error: %w", err)
+		s.logger.Error("request failed", "model", s.modelFor(p), "duration", time.Since(start), "err", err)
+		return "", "", fmt.Errorf("LLM service error: %w", err)
 	}
 
+	metrics.LLMTokens.WithLabelValues(string(model), "prompt").Add(float64(resp.Usage.PromptTokens))
+	metrics.LLMTokens.WithLabelValues(string(model), "completion").Add(float64(resp.Usage.CompletionTokens))
+
 	if len(resp.Choices) == 0 {
-		log.Println("[LLM] No content in response")
-		return "", fmt.Errorf("no response from LLM")
+		s.logger.Warn("no content in response", "model", model)
+		return "", "", fmt.Errorf("no response from LLM")
 	}
 
 	content := resp.Choices[0].Message.Content
-	log.Printf("[LLM] Response received (%d chars)", len(content))
+	s.logger.Info("response received", "model", model, "duration", time.Since(start), "chars", len(content),
+		"images", len(images),
+		"prompt_tokens", resp.Usage.PromptTokens, "completion_tokens", resp.Usage.CompletionTokens)
 
-	return content, nil
+	return content, string(model), nil
 }
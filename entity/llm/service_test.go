@@ -0,0 +1,176 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm/llmtest"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+)
+
+// tokens returns a message that costs n-1 estimated tokens, n with its
+// joining newline.
+func tokens(n int) string {
+	return strings.Repeat("字", n-1)
+}
+
+func TestReduce(t *testing.T) {
+	tests := []struct {
+		name   string
+		sizes  []int
+		budget int
+		// merged is the size of every merge result; a merge that does not
+		// shrink its input must still terminate.
+		merged     int
+		wantMerges int
+	}{
+		{name: "single item", sizes: []int{50}, budget: 100, merged: 10, wantMerges: 0},
+		{name: "one group", sizes: []int{10, 10, 10}, budget: 100, merged: 20, wantMerges: 1},
+		{name: "groups then final merge", sizes: []int{40, 40, 40, 40}, budget: 100, merged: 30, wantMerges: 3},
+		{name: "oversized items pair up", sizes: []int{150, 150, 150, 150}, budget: 100, merged: 150, wantMerges: 3},
+		{name: "odd item passes through", sizes: []int{150, 150, 150}, budget: 100, merged: 150, wantMerges: 2},
+		{name: "growing merges", sizes: []int{60, 60, 60, 60, 60}, budget: 100, merged: 500, wantMerges: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{logger: logging.For(logging.LLM)}
+			var items []string
+			for _, size := range tt.sizes {
+				items = append(items, tokens(size))
+			}
+
+			merges := 0
+			got, err := s.reduce(items, tt.budget, func(group []string) (string, error) {
+				merges++
+				if merges > len(items) {
+					t.Fatalf("%d merges for %d items", merges, len(items))
+				}
+				return tokens(tt.merged), nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if merges != tt.wantMerges {
+				t.Errorf("%d merges, want %d", merges, tt.wantMerges)
+			}
+			if want := tokens(tt.merged); tt.wantMerges > 0 && got != want {
+				t.Errorf("result is not the last merge")
+			}
+		})
+	}
+}
+
+func TestReduceError(t *testing.T) {
+	s := &Service{logger: logging.For(logging.LLM)}
+	failure := errors.New("boom")
+	_, err := s.reduce([]string{"a", "b"}, 100, func([]string) (string, error) {
+		return "", failure
+	})
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), "reduce round 1 group 1") {
+		t.Errorf("err = %v, want the merge error with its round and group", err)
+	}
+}
+
+func TestGenerateSummaryRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		sizes    []int
+		strategy string
+		want     int
+	}{
+		{name: "fits one request", sizes: []int{10, 10}, strategy: "auto", want: 1},
+		{name: "single strategy", sizes: []int{60, 60, 60}, strategy: "single", want: 1},
+		// Five chunks, then one merge of the short partials.
+		{name: "map-reduce", sizes: []int{60, 60, 60, 60, 60}, strategy: "auto", want: 6},
+		{name: "forced map-reduce of one chunk", sizes: []int{10}, strategy: "mapreduce", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, server := newTestService(t, 100)
+			config.Current().SummaryStrategy = tt.strategy
+			var messages []string
+			for _, size := range tt.sizes {
+				messages = append(messages, tokens(size))
+			}
+
+			minutes, model, err := s.GenerateSummary(context.Background(), Profile{}, Meeting{Room: "room"}, messages, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if minutes != testReply || model != "test-model" {
+				t.Errorf("got %q from %q", minutes, model)
+			}
+			if got := len(server.Requests()); got != tt.want {
+				t.Errorf("%d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateDigestRequests(t *testing.T) {
+	tests := []struct {
+		name  string
+		sizes []int
+		want  int
+	}{
+		{name: "one section", sizes: []int{10}, want: 1},
+		{name: "fits one request", sizes: []int{10, 10, 10}, want: 1},
+		// The merge of the pair is the digest; it is not digested again.
+		{name: "two oversized sections", sizes: []int{150, 150}, want: 1},
+		{name: "three oversized sections", sizes: []int{150, 150, 150}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, server := newTestService(t, 100)
+			var sections []string
+			for _, size := range tt.sizes {
+				sections = append(sections, tokens(size))
+			}
+
+			digest, _, err := s.GenerateDigest(context.Background(), Profile{}, "5月1日", sections)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if digest != testReply {
+				t.Errorf("digest %q, want the model reply", digest)
+			}
+			if got := len(server.Requests()); got != tt.want {
+				t.Errorf("%d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+const testReply = "纪要"
+
+// newTestService returns a Service backed by a local server that answers
+// every request with testReply.
+func newTestService(t *testing.T, budget int) (*Service, *llmtest.Server) {
+	t.Helper()
+	server := llmtest.NewServer(t, testReply)
+	prompt := filepath.Join(t.TempDir(), "system_prompt.txt")
+	if err := os.WriteFile(prompt, []byte("你是会议记录员。"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := config.Current()
+	config.Set(&config.Config{
+		LLMAPIKey:          "test",
+		LLMBaseURL:         server.URL,
+		LLMModel:           "test-model",
+		LLMTimeout:         5 * time.Second,
+		SystemPromptFile:   prompt,
+		SummaryStrategy:    "auto",
+		SummaryChunkTokens: budget,
+	})
+	t.Cleanup(func() { config.Set(old) })
+
+	s := New()
+	t.Cleanup(s.Close)
+	return s, server
+}
@@ -0,0 +1,167 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/shared"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+)
+
+// Summary is the structured form of meeting minutes.
+type Summary struct {
+	KeyPoints     []string     `json:"key_points"`
+	Decisions     []string     `json:"decisions"`
+	ActionItems   []ActionItem `json:"action_items"`
+	OpenQuestions []string     `json:"open_questions"`
+}
+
+type ActionItem struct {
+	Owner string `json:"owner"`
+	Task  string `json:"task"`
+	Due   string `json:"due"`
+}
+
+var ErrInvalidJSON = errors.New("LLM response is not valid summary JSON")
+
+var summarySchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"key_points": stringArraySchema,
+		"decisions":  stringArraySchema,
+		"action_items": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"owner": map[string]any{"type": "string"},
+					"task":  map[string]any{"type": "string"},
+					"due":   map[string]any{"type": "string"},
+				},
+				"required":             []string{"owner", "task", "due"},
+				"additionalProperties": false,
+			},
+		},
+		"open_questions": stringArraySchema,
+	},
+	"required":             []string{"key_points", "decisions", "action_items", "open_questions"},
+	"additionalProperties": false,
+}
+
+var stringArraySchema = map[string]any{
+	"type":  "array",
+	"items": map[string]any{"type": "string"},
+}
+
+const jsonInstruction = `
+
+请忽略上文对输出格式的要求，只输出一个 JSON 对象，不要输出其他内容，字段如下：
+- key_points：关键讨论点（字符串数组）
+- decisions：已达成的决定（字符串数组）
+- action_items：待办事项（对象数组，每项包含 owner 负责人、task 任务、due 截止时间；未提及的填空字符串）
+- open_questions：尚未解决的问题（字符串数组）`
+
+// GenerateStructuredSummary returns typed minutes for messages, updating
+// previous minutes when given, and the models that answered. Input over the
+// token budget is condensed with the text pipeline first and then converted.
+func (s *Service) GenerateStructuredSummary(ctx context.Context, p Profile, meeting Meeting, previous string,
+	messages []string, images []Image) (Summary, string, error) {
+	total := EstimateTokens(previous)
+	for _, msg := range messages {
+		total += EstimateTokens(msg) + 1
+	}
+
+	var userPrompt string
+	var attached []Image
+	var models modelSet
+	cfg := config.Current()
+	if cfg.SummaryStrategy == "single" || (cfg.SummaryStrategy == "auto" && total <= cfg.SummaryChunkTokens) {
+		prompt, err := s.userPrompt(p, meeting, previous, 0, 0, messages)
+		if err != nil {
+			return Summary{}, "", err
+		}
+		userPrompt = prompt
+		attached = images
+	} else {
+		minutes, model, err := s.UpdateSummary(ctx, p, meeting, previous, messages, images)
+		if err != nil {
+			return Summary{}, "", err
+		}
+		models.add(model)
+		userPrompt = fmt.Sprintf("请将以下会议纪要整理为结构化结果：\n\n%s", minutes)
+	}
+
+	systemPrompt := s.getSystemPrompt(p.SystemPromptFile) + jsonInstruction
+
+	var format openai.ChatCompletionNewParamsResponseFormatUnion
+	switch cfg.LLMJSONMode {
+	case "schema":
+		format.OfJSONSchema = &shared.ResponseFormatJSONSchemaParam{
+			JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   "meeting_summary",
+				Schema: summarySchema,
+				Strict: openai.Bool(true),
+			},
+		}
+	case "object":
+		format.OfJSONObject = &shared.ResponseFormatJSONObjectParam{}
+	}
+
+	content, model, err := s.completeWith(ctx, p, systemPrompt, userPrompt, attached, format)
+	if err != nil {
+		return Summary{}, "", err
+	}
+	models.add(model)
+
+	summary, err := ParseSummary(content)
+	if err != nil {
+		s.logger.Warn("failed to parse structured summary", "model", model, "err", err)
+		return Summary{}, "", err
+	}
+	return summary, models.String(), nil
+}
+
+// ParseSummary extracts a Summary from a model reply. It tolerates Markdown
+// code fences, text around the JSON object and action items given as plain
+// strings, which is what providers without a JSON mode tend to produce.
+func ParseSummary(content string) (Summary, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return Summary{}, ErrInvalidJSON
+	}
+
+	var raw struct {
+		KeyPoints     []string          `json:"key_points"`
+		Decisions     []string          `json:"decisions"`
+		ActionItems   []json.RawMessage `json:"action_items"`
+		OpenQuestions []string          `json:"open_questions"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &raw); err != nil {
+		return Summary{}, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+
+	summary := Summary{
+		KeyPoints:     raw.KeyPoints,
+		Decisions:     raw.Decisions,
+		OpenQuestions: raw.OpenQuestions,
+	}
+	for _, item := range raw.ActionItems {
+		var action ActionItem
+		if err := json.Unmarshal(item, &action); err == nil {
+			summary.ActionItems = append(summary.ActionItems, action)
+			continue
+		}
+		var task string
+		if err := json.Unmarshal(item, &task); err == nil {
+			summary.ActionItems = append(summary.ActionItems, ActionItem{Task: task})
+			continue
+		}
+		return Summary{}, fmt.Errorf("%w: bad action item %s", ErrInvalidJSON, item)
+	}
+	return summary, nil
+}
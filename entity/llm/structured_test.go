@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Summary
+		wantErr bool
+	}{
+		{
+			name:    "plain object",
+			content: `{"key_points":["上线时间确定"],"decisions":["周五发布"],"action_items":[{"owner":"张三","task":"准备发布说明","due":"周四"}],"open_questions":[]}`,
+			want: Summary{
+				KeyPoints:     []string{"上线时间确定"},
+				Decisions:     []string{"周五发布"},
+				ActionItems:   []ActionItem{{Owner: "张三", Task: "准备发布说明", Due: "周四"}},
+				OpenQuestions: []string{},
+			},
+		},
+		{
+			name:    "code fence and prose",
+			content: "以下是纪要：\n```json\n{\"key_points\":[\"预算\"]}\n```\n如有问题请告知。",
+			want:    Summary{KeyPoints: []string{"预算"}},
+		},
+		{
+			name:    "action items as strings",
+			content: `{"action_items":["更新文档",{"task":"复盘","owner":"李四"}]}`,
+			want:    Summary{ActionItems: []ActionItem{{Task: "更新文档"}, {Owner: "李四", Task: "复盘"}}},
+		},
+		{name: "no object", content: "今天没有讨论。", wantErr: true},
+		{name: "truncated", content: `{"key_points":["预算"`, wantErr: true},
+		{name: "malformed", content: `{"key_points": 预算}`, wantErr: true},
+		{name: "bad action item", content: `{"action_items":[42]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSummary(tt.content)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidJSON) {
+					t.Errorf("err = %v, want ErrInvalidJSON", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
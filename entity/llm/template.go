@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Meeting describes the conversation being summarized. It fills the
+// variables of the user prompt template.
+type Meeting struct {
+	Room         string
+	Start        time.Time
+	End          time.Time
+	Participants []string
+	MessageCount int
+}
+
+// PromptData is what a user prompt template is executed with.
+type PromptData struct {
+	Room string
+	// Date is the day of the first message, e.g. "2025年1月15日".
+	Date string
+	// TimeRange is e.g. "14:00 - 15:30".
+	TimeRange    string
+	Participants []string
+	MessageCount int
+	Language     string
+	// PreviousSummary is set when updating earlier minutes.
+	PreviousSummary string
+	// Part and Parts number the chunk of a map-reduce run; Parts is zero
+	// when the whole transcript fits one request.
+	Part  int
+	Parts int
+	// Messages is the transcript, one "[HH:MM] sender: text" line per message.
+	Messages string
+}
+
+// defaultTemplate reproduces the prompts used before templates existed.
+const defaultTemplate = `{{- if .Parts -}}
+以下是一段群聊记录的第 {{.Part}}/{{.Parts}} 部分，请为这部分消息生成会议纪要：
+{{- else if .PreviousSummary -}}
+以下是本群今天此前的会议纪要：
+
+{{.PreviousSummary}}
+
+请结合之后的新群聊消息，输出更新后的完整会议纪要（保留此前仍然有效的内容）：
+{{- else -}}
+请为以下群聊消息生成会议纪要：
+{{- end}}
+
+{{.Messages}}`
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// parseTemplate parses and validates a user prompt template. It is executed
+// once for each kind of request so that unknown variables and templates that
+// would drop the transcript are caught on load rather than at summary time.
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	const transcript = "[09:00] 张三: 测试消息"
+	sample := PromptData{
+		Room:         "示例群",
+		Date:         "2025年1月15日",
+		TimeRange:    "09:00 - 10:00",
+		Participants: []string{"张三", "李四"},
+		MessageCount: 1,
+		Language:     "中文",
+		Messages:     transcript,
+	}
+	withPrevious := sample
+	withPrevious.PreviousSummary = "此前的会议纪要"
+	chunk := sample
+	chunk.Part, chunk.Parts = 1, 2
+
+	for _, data := range []PromptData{sample, withPrevious, chunk} {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return nil, err
+		}
+		if !strings.Contains(sb.String(), transcript) {
+			return nil, fmt.Errorf("template must include {{.Messages}}")
+		}
+	}
+	return tmpl, nil
+}
+
+func (s *Service) loadTemplate(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read prompt template: %w", err)
+	}
+
+	tmpl, err := parseTemplate(filepath.Base(path), string(data))
+	if err != nil {
+		return fmt.Errorf("invalid prompt template %s: %w", path, err)
+	}
+	s.templates.Set(filepath.Clean(path), tmpl)
+
+	s.logger.Info("prompt template loaded", "file", path, "chars", len(data))
+	return nil
+}
+
+func (s *Service) getTemplate(path string) *template.Template {
+	if path != "" {
+		if tmpl, ok := s.templates.Get(filepath.Clean(path)); ok {
+			return tmpl
+		}
+	}
+	return s.defaultTemplate
+}
+
+// userPrompt renders the template of p for meeting. previous, part and
+// parts are only set for rolling updates and map-reduce chunks.
+func (s *Service) userPrompt(p Profile, meeting Meeting, previous string, part, parts int, messages []string) (string, error) {
+	data := PromptData{
+		Room:            meeting.Room,
+		Participants:    meeting.Participants,
+		MessageCount:    meeting.MessageCount,
+		Language:        p.Language,
+		PreviousSummary: previous,
+		Part:            part,
+		Parts:           parts,
+		Messages:        strings.Join(messages, "\n"),
+	}
+	if !meeting.Start.IsZero() {
+		data.Date = meeting.Start.Format("2006年1月2日")
+		data.TimeRange = fmt.Sprintf("%s - %s", meeting.Start.Format("15:04"), meeting.End.Format("15:04"))
+	}
+
+	var sb strings.Builder
+	if err := s.getTemplate(p.PromptTemplateFile).Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTemplate(t *testing.T) {
+	example, err := os.ReadFile("../../prompt_template.example.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		text string
+		// wantErr is contained in the error; empty for a valid template.
+		wantErr string
+	}{
+		{name: "built-in", text: defaultTemplate},
+		{name: "example", text: string(example)},
+		{name: "functions", text: `{{join .Participants "、"}} {{len .Participants}}: {{.Messages}}`},
+		{name: "no transcript", text: "请总结 {{.Room}}", wantErr: "must include {{.Messages}}"},
+		{
+			name:    "transcript only for chunks",
+			text:    "{{if .Parts}}{{.Messages}}{{else}}请总结{{end}}",
+			wantErr: "must include {{.Messages}}",
+		},
+		{name: "unknown variable", text: "{{.Topic}} {{.Messages}}", wantErr: "Topic"},
+		{name: "unknown function", text: "{{upper .Room}} {{.Messages}}", wantErr: "upper"},
+		{name: "syntax error", text: "{{.Room {{.Messages}}", wantErr: "template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTemplate(tt.name, tt.text)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserPrompt(t *testing.T) {
+	s, _ := newTestService(t, 1000)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prompt_template.txt")
+	text := "{{.Room}}|{{.Date}}|{{.TimeRange}}|{{join .Participants \",\"}}|{{.MessageCount}}|{{.Language}}|{{.Part}}/{{.Parts}}\n{{.Messages}}"
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.loadTemplate(path); err != nil {
+		t.Fatal(err)
+	}
+
+	meeting := Meeting{
+		Room:         "周会",
+		Start:        time.Date(2025, 1, 15, 9, 5, 0, 0, time.UTC),
+		End:          time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC),
+		Participants: []string{"alice", "bob"},
+		MessageCount: 2,
+	}
+	tests := []struct {
+		name    string
+		profile Profile
+		part    int
+		parts   int
+		want    string
+	}{
+		{
+			name:    "custom template",
+			profile: Profile{PromptTemplateFile: path, Language: "English"},
+			want:    "周会|2025年1月15日|09:05 - 10:30|alice,bob|2|English|0/0\nm1\nm2",
+		},
+		{
+			name:    "chunk",
+			profile: Profile{PromptTemplateFile: filepath.Join(dir, ".", "prompt_template.txt"), Language: "中文"},
+			part:    2, parts: 3,
+			want: "周会|2025年1月15日|09:05 - 10:30|alice,bob|2|中文|2/3\nm1\nm2",
+		},
+		{name: "built-in", profile: Profile{}, want: "请为以下群聊消息生成会议纪要：\n\nm1\nm2"},
+		{name: "unloaded template falls back", profile: Profile{PromptTemplateFile: "missing.txt"}, want: "请为以下群聊消息生成会议纪要：\n\nm1\nm2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.userPrompt(tt.profile, meeting, "", tt.part, tt.parts, []string{"m1", "m2"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("prompt\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTemplateErrors(t *testing.T) {
+	s, _ := newTestService(t, 1000)
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.txt")
+	if err := os.WriteFile(invalid, []byte("请总结 {{.Room}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.loadTemplate(filepath.Join(dir, "missing.txt")); err == nil || !strings.Contains(err.Error(), "failed to read") {
+		t.Errorf("missing file: err = %v", err)
+	}
+	if err := s.loadTemplate(invalid); err == nil || !strings.Contains(err.Error(), invalid) {
+		t.Errorf("invalid template: err = %v, want one naming the file", err)
+	}
+	if _, ok := s.templates.Get(invalid); ok {
+		t.Error("an invalid template was stored")
+	}
+}
@@ -0,0 +1,51 @@
+package llm
+
+import "unicode"
+
+// EstimateTokens approximates the token count of mixed Chinese/English text.
+// CJK characters cost roughly one token each; other text averages about four
+// bytes per token.
+func EstimateTokens(text string) int {
+	cjk := 0
+	other := 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r),
+			unicode.Is(unicode.Hiragana, r),
+			unicode.Is(unicode.Katakana, r),
+			unicode.Is(unicode.Hangul, r):
+			cjk++
+		case unicode.IsSpace(r):
+			other++
+		case r < unicode.MaxASCII:
+			other++
+		default:
+			// Full-width punctuation, emoji and the like are usually a token each.
+			cjk++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// SplitByTokens groups consecutive messages into chunks of at most budget
+// estimated tokens. A single message larger than the budget gets its own chunk.
+func SplitByTokens(messages []string, budget int) [][]string {
+	var chunks [][]string
+	var current []string
+	used := 0
+	for _, msg := range messages {
+		// +1 for the newline joining messages.
+		cost := EstimateTokens(msg) + 1
+		if len(current) > 0 && used+cost > budget {
+			chunks = append(chunks, current)
+			current = nil
+			used = 0
+		}
+		current = append(current, msg)
+		used += cost
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "", want: 0},
+		{text: "hello world", want: 3},
+		{text: "会议纪要", want: 4},
+		{text: "发布 v2", want: 3},
+		{text: "好的！", want: 3},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSplitByTokens(t *testing.T) {
+	// Each message costs its length in Han characters plus one for the
+	// joining newline.
+	msg := func(tokens int) string { return strings.Repeat("字", tokens) }
+	tests := []struct {
+		name   string
+		sizes  []int
+		budget int
+		want   []int
+	}{
+		{name: "empty", sizes: nil, budget: 10, want: nil},
+		{name: "fits one chunk", sizes: []int{3, 3, 2}, budget: 11, want: []int{3}},
+		{name: "exact budget", sizes: []int{4, 4}, budget: 10, want: []int{2}},
+		{name: "split", sizes: []int{4, 4, 4}, budget: 10, want: []int{2, 1}},
+		{name: "oversized message alone", sizes: []int{2, 20, 2}, budget: 10, want: []int{1, 1, 1}},
+		{name: "every message oversized", sizes: []int{20, 20}, budget: 10, want: []int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages []string
+			for _, size := range tt.sizes {
+				messages = append(messages, msg(size))
+			}
+
+			chunks := SplitByTokens(messages, tt.budget)
+
+			var got []int
+			var joined []string
+			for _, chunk := range chunks {
+				got = append(got, len(chunk))
+				joined = append(joined, chunk...)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("chunk sizes %v, want %v", got, tt.want)
+			}
+			if strings.Join(joined, "\n") != strings.Join(messages, "\n") {
+				t.Error("chunks do not keep every message in order")
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"path/filepath"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/filewatch"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+)
+
+// startWatcher reloads prompt files and templates when they change.
+func (s *Service) startWatcher(files []string) {
+	watcher, err := filewatch.New(s.logger, func(paths []string) {
+		for _, path := range paths {
+			s.reload(path)
+		}
+	})
+	if err != nil {
+		logging.Fatal(s.logger, "failed to create file watcher", "err", err)
+	}
+	s.watcher = watcher
+
+	for _, path := range files {
+		if err := watcher.Add(path); err != nil {
+			watcher.Close()
+			logging.Fatal(s.logger, "failed to watch prompt file", "file", path, "err", err)
+		}
+	}
+	s.logger.Info("file watcher started", "files", files)
+}
+
+// reload rereads a changed prompt file. If the new version is missing,
+// empty or invalid, the previous one stays in use until the file is fixed.
+func (s *Service) reload(path string) {
+	if _, ok := s.templates.Get(path); ok {
+		s.logger.Info("prompt template changed, reloading", "file", path)
+		if err := s.loadTemplate(path); err != nil {
+			s.logger.Error("failed to reload prompt template, keeping the previous one", "file", path, "err", err)
+		}
+		return
+	}
+	s.logger.Info("system prompt file changed, reloading", "file", path)
+	if err := s.loadSystemPrompt(path); err != nil {
+		s.logger.Error("failed to reload system prompt, keeping the previous one", "file", path, "err", err)
+	}
+}
+
+// Prepare loads the prompt files and templates that next refers to and the
+// running configuration does not, so a reload that names a missing or
+// invalid prompt is rejected before it takes effect.
+func (s *Service) Prepare(next *config.Config) error {
+	for _, path := range next.SystemPromptFiles() {
+		if _, ok := s.systemPrompts.Get(filepath.Clean(path)); ok {
+			continue
+		}
+		if err := s.loadSystemPrompt(path); err != nil {
+			return err
+		}
+	}
+	for _, path := range next.PromptTemplateFiles() {
+		if _, ok := s.templates.Get(filepath.Clean(path)); ok {
+			continue
+		}
+		if err := s.loadTemplate(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply switches to the endpoints of next once it is current, and watches
+// any prompt files it added.
+func (s *Service) Apply(old, next *config.Config) {
+	if old.LLMChanged(next) {
+		providers := newProviders()
+		s.providers.Store(&providers)
+		s.logger.Info("LLM providers updated", "base_url", next.LLMBaseURL, "fallbacks", len(next.LLMFallbacks))
+	}
+	for _, path := range append(next.SystemPromptFiles(), next.PromptTemplateFiles()...) {
+		if err := s.watcher.Add(path); err != nil {
+			s.logger.Error("failed to watch prompt file", "file", path, "err", err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Subsystem names used across the application.
+const (
+	Main    = "main"
+	Config  = "config"
+	Bot     = "bot"
+	Chat    = "chat"
+	Buffer  = "buffer"
+	LLM     = "llm"
+	Summary = "summary"
+	Admin   = "admin"
+	Metrics = "metrics"
+	Replay  = "replay"
+	STT     = "stt"
+)
+
+var (
+	mu           sync.Mutex
+	base         slog.Handler = newHandler(os.Stderr, "text")
+	defaultLevel              = new(slog.LevelVar)
+	overrides                 = map[string]slog.Level{}
+	levels                    = map[string]*slog.LevelVar{}
+)
+
+func newHandler(w io.Writer, format string) slog.Handler {
+	// Level filtering happens per subsystem in levelHandler.
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug - 4}
+	if format == "json" {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// Setup configures the output format ("text" or "json"), the default level
+// and per-subsystem levels given as "buffer=debug,llm=warn". Loggers created
+// earlier keep the previous output but pick up the new levels.
+func Setup(format, level, subsystemLevels string) error {
+	if format != "text" && format != "json" {
+		return fmt.Errorf("log format must be 'text' or 'json', got '%s'", format)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	base = newHandler(os.Stderr, format)
+	if err := setLevelsLocked(level, subsystemLevels); err != nil {
+		return err
+	}
+
+	slog.SetDefault(newLogger(Main))
+	return nil
+}
+
+// SetLevels changes the default and per-subsystem levels of all loggers.
+func SetLevels(level, subsystemLevels string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	return setLevelsLocked(level, subsystemLevels)
+}
+
+func setLevelsLocked(level, subsystemLevels string) error {
+	def, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	parsed := map[string]slog.Level{}
+	for _, item := range strings.Split(subsystemLevels, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, lvl, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("invalid subsystem level '%s', want name=level", item)
+		}
+		l, err := parseLevel(lvl)
+		if err != nil {
+			return err
+		}
+		parsed[strings.TrimSpace(name)] = l
+	}
+
+	defaultLevel.Set(def)
+	overrides = parsed
+	for name, lv := range levels {
+		lv.Set(levelFor(name))
+	}
+	return nil
+}
+
+func parseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("invalid log level '%s'", s)
+	}
+	return l, nil
+}
+
+func levelFor(subsystem string) slog.Level {
+	if l, ok := overrides[subsystem]; ok {
+		return l
+	}
+	return defaultLevel.Level()
+}
+
+// For returns the logger of a subsystem. Every record carries a
+// "subsystem" attribute and is filtered by that subsystem's level.
+func For(subsystem string) *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	return newLogger(subsystem)
+}
+
+func newLogger(subsystem string) *slog.Logger {
+	lv, ok := levels[subsystem]
+	if !ok {
+		lv = new(slog.LevelVar)
+		lv.Set(levelFor(subsystem))
+		levels[subsystem] = lv
+	}
+	return slog.New(&levelHandler{
+		level:   lv,
+		handler: base.WithAttrs([]slog.Attr{slog.String("subsystem", subsystem)}),
+	})
+}
+
+type levelHandler struct {
+	level   slog.Leveler
+	handler slog.Handler
+}
+
+func (h *levelHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{level: h.level, handler: h.handler.WithGroup(name)}
+}
+
+// Fatal logs at error level and exits, for unrecoverable startup failures.
+func Fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
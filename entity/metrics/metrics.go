@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+)
+
+const namespace = "scribe"
+
+// OtherRoom is the room label of messages from rooms the bot does not
+// watch, so that every group the account is in does not become a series.
+const OtherRoom = "other"
+
+var (
+	MessagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Group messages received from the chat platform.",
+	}, []string{"room"})
+
+	MessagesFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_filtered_total",
+		Help:      "Group messages ignored before buffering, by reason.",
+	}, []string{"room", "reason"})
+
+	MessagesBuffered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_buffered_total",
+		Help:      "Messages added to a room buffer.",
+	}, []string{"room"})
+
+	DuplicatesSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "buffer_duplicates_skipped_total",
+		Help:      "Messages skipped by the buffer because their ID was already present.",
+	}, []string{"room"})
+
+	SummariesTriggered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "summaries_triggered_total",
+		Help:      "Summaries triggered, by cause (keyword, count, interval, manual).",
+	}, []string{"room", "cause"})
+
+	SummariesGenerated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "summaries_generated_total",
+		Help:      "Summary generation attempts, by outcome.",
+	}, []string{"room", "outcome"})
+
+	QueueDrops = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "summary_queue_drops_total",
+		Help:      "Summary requests dropped because the queue was full, by source.",
+	}, []string{"source"})
+
+	SummariesCoalesced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "summaries_coalesced_total",
+		Help:      "Summary requests merged into one already queued for the room.",
+	}, []string{"room"})
+
+	CommandsHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_handled_total",
+		Help:      "Chat commands addressed to the bot, by outcome (ok, denied, invalid).",
+	}, []string{"command", "outcome"})
+
+	DigestsGenerated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "digests_generated_total",
+		Help:      "Cross-room digest runs, by outcome (ok, empty, error, undelivered).",
+	}, []string{"outcome"})
+
+	VoiceTranscriptions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "voice_transcriptions_total",
+		Help:      "Voice messages sent to speech-to-text, by outcome.",
+	}, []string{"outcome"})
+
+	LLMRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "Latency of chat completion requests.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"model", "outcome"})
+
+	LLMTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens reported by the LLM provider, by kind (prompt, completion).",
+	}, []string{"model", "kind"})
+
+	LLMRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_retries_total",
+		Help:      "Chat completion requests retried after a retryable error.",
+	}, []string{"model"})
+
+	LLMFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_fallbacks_total",
+		Help:      "Requests answered by a fallback provider, by fallback model.",
+	}, []string{"model"})
+
+	DeliveryFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "delivery_failures_total",
+		Help:      "Failed summary or report deliveries, by sink kind.",
+	}, []string{"sink"})
+
+	ConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_reloads_total",
+		Help:      "Configuration reloads, by outcome (ok, error).",
+	}, []string{"outcome"})
+)
+
+// Server exposes /metrics in the Prometheus text format.
+type Server struct {
+	server *http.Server
+	logger *slog.Logger
+}
+
+func NewServer(addr string) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+	return &Server{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		logger: logging.For(logging.Metrics),
+	}
+}
+
+func (s *Server) Start() {
+	go func() {
+		s.logger.Info("serving /metrics", "addr", s.server.Addr)
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("HTTP server error", "err", err)
+		}
+	}()
+}
+
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Error("failed to shut down HTTP server", "err", err)
+	}
+}
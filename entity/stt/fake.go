@@ -0,0 +1,19 @@
+package stt
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// Fake "transcribes" path by reading the sidecar text file path + ".txt".
+// It is meant for local demos and replays without an STT service.
+type Fake struct{}
+
+func (Fake) Transcribe(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path + ".txt")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,67 @@
+package stt
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+)
+
+// OpenAI transcribes through an OpenAI-compatible /audio/transcriptions
+// endpoint, such as OpenAI Whisper, Groq or a local whisper.cpp server.
+type OpenAI struct {
+	client   openai.Client
+	model    string
+	language string
+	timeout  time.Duration
+	logger   *slog.Logger
+}
+
+func NewOpenAI() *OpenAI {
+	cfg := config.Current()
+	return &OpenAI{
+		client: openai.NewClient(
+			option.WithAPIKey(cfg.STTAPIKey),
+			option.WithBaseURL(cfg.STTBaseURL),
+		),
+		model:    cfg.STTModel,
+		language: cfg.STTLanguage,
+		timeout:  cfg.STTTimeout,
+		logger:   logging.For(logging.STT),
+	}
+}
+
+func (o *OpenAI) Transcribe(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	params := openai.AudioTranscriptionNewParams{
+		File:  f,
+		Model: openai.AudioModel(o.model),
+	}
+	if o.language != "" {
+		params.Language = openai.String(o.language)
+	}
+
+	start := time.Now()
+	resp, err := o.client.Audio.Transcriptions.New(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("transcription request failed: %w", err)
+	}
+	text := strings.TrimSpace(resp.Text)
+	o.logger.Debug("transcription received", "model", o.model, "duration", time.Since(start), "chars", len(text))
+	return text, nil
+}
@@ -0,0 +1,26 @@
+package stt
+
+import (
+	"context"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+)
+
+// Transcriber turns a recorded voice message into text.
+type Transcriber interface {
+	// Transcribe returns the text spoken in the audio file at path.
+	Transcribe(ctx context.Context, path string) (string, error)
+}
+
+// New returns the transcriber selected by STT_PROVIDER, or nil when speech
+// to text is disabled.
+func New() Transcriber {
+	switch config.Current().STTProvider {
+	case "openai":
+		return NewOpenAI()
+	case "fake":
+		return Fake{}
+	default:
+		return nil
+	}
+}
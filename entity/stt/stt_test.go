@@ -0,0 +1,92 @@
+package stt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+)
+
+func TestFake(t *testing.T) {
+	dir := t.TempDir()
+	voice := filepath.Join(dir, "voice-1.mp3")
+	if err := os.WriteFile(voice+".txt", []byte(" 周五发布\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if text, err := (Fake{}).Transcribe(context.Background(), voice); err != nil || text != "周五发布" {
+		t.Errorf("Transcribe = %q, %v", text, err)
+	}
+	if _, err := (Fake{}).Transcribe(context.Background(), filepath.Join(dir, "missing.mp3")); err == nil {
+		t.Error("Transcribe succeeded without a sidecar file")
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		status   int
+		want     string
+		wantErr  bool
+	}{
+		{name: "transcribed", language: "zh", status: http.StatusOK, want: "周五发布"},
+		{name: "detected language", status: http.StatusOK, want: "周五发布"},
+		{name: "rejected", language: "zh", status: http.StatusBadRequest, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var model, language, file string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/audio/transcriptions" {
+					http.NotFound(w, r)
+					return
+				}
+				model, language = r.FormValue("model"), r.FormValue("language")
+				if _, header, err := r.FormFile("file"); err == nil {
+					file = header.Filename
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				if tt.status == http.StatusOK {
+					w.Write([]byte(`{"text":" 周五发布 "}`))
+				} else {
+					w.Write([]byte(`{"error":{"message":"bad audio"}}`))
+				}
+			}))
+			defer server.Close()
+
+			old := config.Current()
+			config.Set(&config.Config{
+				STTProvider: "openai",
+				STTBaseURL:  server.URL + "/",
+				STTAPIKey:   "test",
+				STTModel:    "whisper-1",
+				STTLanguage: tt.language,
+				STTTimeout:  5 * time.Second,
+			})
+			t.Cleanup(func() { config.Set(old) })
+
+			voice := filepath.Join(t.TempDir(), "voice-1.mp3")
+			if err := os.WriteFile(voice, []byte("\xff\xfb\x90\x00"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			text, err := New().Transcribe(context.Background(), voice)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if text != tt.want {
+				t.Errorf("text %q, want %q", text, tt.want)
+			}
+			if model != "whisper-1" || language != tt.language || file != "voice-1.mp3" {
+				t.Errorf("request model %q language %q file %q", model, language, file)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+)
+
+var (
+	ErrUnknownRoom = errors.New("unknown room")
+	ErrQueueFull   = errors.New("summary queue is full")
+)
+
+// Controller is the part of the bot exposed over HTTP.
+type Controller interface {
+	RoomStats() []buffer.RoomStats
+	Snapshot(room string) (buffer.Snapshot, bool)
+	// TriggerSummary queues a summary and returns the room's queue position.
+	TriggerSummary(room string) (int, error)
+	ClearRoom(room string) bool
+	QueueStats() QueueStatus
+}
+
+// QueueStatus describes the summary queue. Pending is in queue order.
+type QueueStatus struct {
+	Depth    int      `json:"depth"`
+	Capacity int      `json:"capacity"`
+	Workers  int      `json:"workers"`
+	Pending  []string `json:"pending"`
+	Running  []string `json:"running"`
+}
+
+type Server struct {
+	ctrl   Controller
+	token  string
+	server *http.Server
+	logger *slog.Logger
+}
+
+func New(addr, token string, ctrl Controller) *Server {
+	s := &Server{
+		ctrl:   ctrl,
+		token:  token,
+		logger: logging.For(logging.Admin),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/rooms", s.handleRooms)
+	mux.HandleFunc("GET /api/rooms/{room}/snapshot", s.handleSnapshot)
+	mux.HandleFunc("POST /api/rooms/{room}/summary", s.handleTrigger)
+	mux.HandleFunc("POST /api/rooms/{room}/clear", s.handleClear)
+	mux.HandleFunc("GET /api/queue", s.handleQueue)
+
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           s.authenticate(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Start serves in the background until Shutdown.
+func (s *Server) Start() {
+	go func() {
+		s.logger.Info("HTTP API listening", "addr", s.server.Addr)
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("HTTP server error", "err", err)
+		}
+	}()
+}
+
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Error("failed to shut down HTTP server", "err", err)
+	}
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			s.writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleRooms(w http.ResponseWriter, _ *http.Request) {
+	stats := s.ctrl.RoomStats()
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Room < stats[j].Room })
+	s.writeJSON(w, http.StatusOK, stats)
+}
+
+type snapshotView struct {
+	Room         string     `json:"room"`
+	Count        int        `json:"count"`
+	FirstMsgTime *time.Time `json:"first_msg_time,omitempty"`
+	LastMsgTime  *time.Time `json:"last_msg_time,omitempty"`
+	Participants []string   `json:"participants"`
+	Messages     []string   `json:"messages"`
+}
+
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	room := r.PathValue("room")
+	snapshot, ok := s.ctrl.Snapshot(room)
+	if !ok {
+		s.writeError(w, http.StatusNotFound, ErrUnknownRoom.Error())
+		return
+	}
+
+	participants := make([]string, 0, len(snapshot.Participants))
+	for p := range snapshot.Participants {
+		participants = append(participants, p)
+	}
+	sort.Strings(participants)
+
+	s.writeJSON(w, http.StatusOK, snapshotView{
+		Room:         room,
+		Count:        snapshot.Count,
+		FirstMsgTime: snapshot.FirstMsgTime,
+		LastMsgTime:  snapshot.LastMsgTime,
+		Participants: participants,
+		Messages:     snapshot.FormattedMsg,
+	})
+}
+
+func (s *Server) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	room := r.PathValue("room")
+	position, err := s.ctrl.TriggerSummary(room)
+	switch {
+	case errors.Is(err, ErrUnknownRoom):
+		s.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrQueueFull):
+		s.writeError(w, http.StatusServiceUnavailable, err.Error())
+	case err != nil:
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		s.logger.Info("summary requested", "room", room, "position", position)
+		s.writeJSON(w, http.StatusAccepted, map[string]any{"status": "queued", "room": room, "position": position})
+	}
+}
+
+func (s *Server) handleClear(w http.ResponseWriter, r *http.Request) {
+	room := r.PathValue("room")
+	if !s.ctrl.ClearRoom(room) {
+		s.writeError(w, http.StatusNotFound, ErrUnknownRoom.Error())
+		return
+	}
+	s.logger.Info("buffer cleared", "room", room)
+	s.writeJSON(w, http.StatusOK, map[string]string{"status": "cleared", "room": room})
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.ctrl.QueueStats())
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Error("failed to write response", "err", err)
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, msg string) {
+	s.writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+)
+
+// fakeController knows one room, "周会", holding two messages.
+type fakeController struct {
+	triggerErr error
+	triggered  []string
+	cleared    []string
+}
+
+func (c *fakeController) RoomStats() []buffer.RoomStats {
+	return []buffer.RoomStats{{Room: "周会", Count: 2, Capacity: 50}, {Room: "早会", Capacity: 50}}
+}
+
+func (c *fakeController) Snapshot(room string) (buffer.Snapshot, bool) {
+	if room != "周会" {
+		return buffer.Snapshot{}, false
+	}
+	return buffer.Snapshot{
+		Count:        2,
+		Participants: map[string]struct{}{"bob": {}, "alice": {}},
+		FormattedMsg: []string{"alice: 早", "bob: 早"},
+	}, true
+}
+
+func (c *fakeController) TriggerSummary(room string) (int, error) {
+	if c.triggerErr != nil {
+		return 0, c.triggerErr
+	}
+	if room != "周会" {
+		return 0, ErrUnknownRoom
+	}
+	c.triggered = append(c.triggered, room)
+	return 1, nil
+}
+
+func (c *fakeController) ClearRoom(room string) bool {
+	if room != "周会" {
+		return false
+	}
+	c.cleared = append(c.cleared, room)
+	return true
+}
+
+func (c *fakeController) QueueStats() QueueStatus {
+	return QueueStatus{Capacity: 100, Workers: 2, Pending: []string{}, Running: []string{}}
+}
+
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{name: "valid token", header: "Bearer s3cret", want: http.StatusOK},
+		{name: "missing header", want: http.StatusUnauthorized},
+		{name: "wrong token", header: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "token prefix", header: "Bearer s3cre", want: http.StatusUnauthorized},
+		{name: "not bearer", header: "Basic s3cret", want: http.StatusUnauthorized},
+		{name: "empty bearer", header: "Bearer ", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &fakeController{}
+			rec := serve(New("", "s3cret", ctrl), http.MethodPost, "/api/rooms/周会/clear", tt.header)
+			if rec.Code != tt.want {
+				t.Fatalf("status %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized {
+				if rec.Header().Get("WWW-Authenticate") != "Bearer" {
+					t.Error("missing WWW-Authenticate challenge")
+				}
+				if len(ctrl.cleared) != 0 {
+					t.Error("unauthorized request reached the controller")
+				}
+			}
+		})
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		triggerErr error
+		want       int
+		// wantBody is contained in the response.
+		wantBody string
+	}{
+		{name: "rooms", method: http.MethodGet, path: "/api/rooms", want: http.StatusOK, wantBody: `"room":"早会"`},
+		{
+			name: "snapshot", method: http.MethodGet, path: "/api/rooms/周会/snapshot", want: http.StatusOK,
+			wantBody: `"participants":["alice","bob"]`,
+		},
+		{name: "snapshot unknown room", method: http.MethodGet, path: "/api/rooms/x/snapshot", want: http.StatusNotFound},
+		{name: "trigger", method: http.MethodPost, path: "/api/rooms/周会/summary", want: http.StatusAccepted, wantBody: `"position":1`},
+		{name: "trigger unknown room", method: http.MethodPost, path: "/api/rooms/x/summary", want: http.StatusNotFound},
+		{
+			name: "trigger queue full", method: http.MethodPost, path: "/api/rooms/周会/summary",
+			triggerErr: ErrQueueFull, want: http.StatusServiceUnavailable,
+		},
+		{name: "clear unknown room", method: http.MethodPost, path: "/api/rooms/x/clear", want: http.StatusNotFound},
+		{name: "queue", method: http.MethodGet, path: "/api/queue", want: http.StatusOK, wantBody: `"workers":2`},
+		{name: "wrong method", method: http.MethodGet, path: "/api/rooms/周会/summary", want: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &fakeController{triggerErr: tt.triggerErr}
+			rec := serve(New("", "s3cret", ctrl), tt.method, tt.path, "Bearer s3cret")
+			if rec.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body %s does not contain %s", rec.Body, tt.wantBody)
+			}
+			if rec.Code >= 400 && rec.Code != http.StatusMethodNotAllowed {
+				var body map[string]string
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] == "" {
+					t.Errorf("error body %s, want a JSON error", rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func serve(s *Server, method, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", nil)
+	req.URL.Path = path
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
+	"github.com/soaringk/wechat-meeting-scribe/logic/admin"
+)
+
+// The methods below implement admin.Controller.
+
+func (b *Bot) RoomStats() []buffer.RoomStats {
+	return b.buffer.Stats()
+}
+
+func (b *Bot) Snapshot(room string) (buffer.Snapshot, bool) {
+	if !b.buffer.HasRoom(room) {
+		return buffer.Snapshot{}, false
+	}
+	return b.buffer.GetSnapshot(room), true
+}
+
+// TriggerSummary enqueues a summary for room regardless of its triggers.
+func (b *Bot) TriggerSummary(room string) (int, error) {
+	return b.triggerSummary(room, "manual")
+}
+
+// triggerSummary enqueues a summary on behalf of source (manual or command).
+func (b *Bot) triggerSummary(room, source string) (int, error) {
+	if !b.buffer.HasRoom(room) {
+		return 0, admin.ErrUnknownRoom
+	}
+	position, ok := b.enqueueSummary(room, source)
+	if !ok {
+		metrics.QueueDrops.WithLabelValues(source).Inc()
+		return 0, admin.ErrQueueFull
+	}
+	metrics.SummariesTriggered.WithLabelValues(room, source).Inc()
+	return position, nil
+}
+
+func (b *Bot) ClearRoom(room string) bool {
+	if !b.buffer.HasRoom(room) {
+		return false
+	}
+	b.buffer.Clear(room)
+	return true
+}
+
+func (b *Bot) QueueStats() admin.QueueStatus {
+	pending, running := b.summaryQueue.Status()
+	cfg := config.Current()
+	return admin.QueueStatus{
+		Depth:    len(pending),
+		Capacity: cfg.SummaryQueueSize,
+		Workers:  cfg.SummaryWorkers,
+		Pending:  pending,
+		Running:  running,
+	}
+}
@@ -2,240 +2,470 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/eatmoreapple/openwechat"
+	"github.com/robfig/cron/v3"
+	"github.com/soaringk/wechat-meeting-scribe/entity/archive"
 	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
 	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/filewatch"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
+	"github.com/soaringk/wechat-meeting-scribe/entity/stt"
+	"github.com/soaringk/wechat-meeting-scribe/logic/admin"
+	"github.com/soaringk/wechat-meeting-scribe/logic/sink"
 	"github.com/soaringk/wechat-meeting-scribe/logic/summary"
 )
 
 type Bot struct {
-	bot          *openwechat.Bot
+	platform     chat.Platform
 	buffer       *buffer.MessageBuffer
 	generator    *summary.Generator
-	self         *openwechat.Self
+	archive      *archive.Archive
+	admin        *admin.Server
+	metrics      *metrics.Server
 	stopTimer    chan struct{}
-	summaryQueue chan string
-	stopOnce     sync.Once
-	ctx          context.Context
-	cancel       context.CancelFunc
+	cron         *cron.Cron
+	configWatch  *filewatch.Watcher
+	transcriber  stt.Transcriber
+	summaryQueue *roomQueue
+	media        chan chat.Message
+	workers      sync.WaitGroup
+	// tasks tracks on-demand work started by commands and scheduler jobs.
+	tasks    sync.WaitGroup
+	pausedMu sync.Mutex
+	paused   map[string]bool
+	// reloadMu serializes configuration reloads with starting and stopping
+	// the interval timer, scheduler and config watcher.
+	reloadMu sync.Mutex
+	stopOnce sync.Once
+	ctx      context.Context
+	cancel   context.CancelFunc
+	logger   *slog.Logger
 }
 
 func New() *Bot {
+	var platform chat.Platform
+	switch config.Current().ChatPlatform {
+	case "fake":
+		fake := chat.NewFake()
+		fake.Input = os.Stdin
+		fake.Output = os.Stdout
+		platform = fake
+	default:
+		platform = chat.NewWeChat()
+	}
+	return NewWithPlatform(platform)
+}
+
+func NewWithPlatform(platform chat.Platform) *Bot {
 	ctx, cancel := context.WithCancel(context.Background())
+	logger := logging.For(logging.Bot)
+
+	var summaryArchive *archive.Archive
+	cfg := config.Current()
+	if cfg.ArchiveFile != "" {
+		a, err := archive.Open(cfg.ArchiveFile)
+		if err != nil {
+			logging.Fatal(logger, "failed to open summary archive", "file", cfg.ArchiveFile, "err", err)
+		}
+		summaryArchive = a
+	}
 
-	return &Bot{
-		bot:          openwechat.DefaultBot(openwechat.Desktop),
+	b := &Bot{
+		platform:     platform,
 		buffer:       buffer.New(),
 		generator:    summary.New(),
-		stopTimer:    make(chan struct{}),
-		summaryQueue: make(chan string, config.AppConfig.SummaryQueueSize),
+		archive:      summaryArchive,
+		transcriber:  stt.New(),
+		summaryQueue: newRoomQueue(cfg.SummaryQueueSize),
+		media:        make(chan chat.Message, mediaQueueSize),
+		paused:       make(map[string]bool),
 		ctx:          ctx,
 		cancel:       cancel,
+		logger:       logger,
 	}
+
+	if cfg.AdminAddr != "" {
+		b.admin = admin.New(cfg.AdminAddr, cfg.AdminToken, b)
+	}
+	if cfg.MetricsAddr != "" {
+		b.metrics = metrics.NewServer(cfg.MetricsAddr)
+	}
+	return b
 }
 
 func (b *Bot) Start() error {
-	log.Println("🤖 Initializing WeChat Meeting Scribe...")
-
-	b.bot.UUIDCallback = openwechat.PrintlnQrcodeUrl
-
-	b.bot.MessageHandler = b.handleMessage
-
-	reloadStorage := openwechat.NewFileHotReloadStorage("storage.json")
-	defer reloadStorage.Close()
+	b.logger.Info("starting bot", "platform", config.Current().ChatPlatform)
 
-	log.Println("🚀 Starting bot...")
-	log.Println("⏳ Attempting hot login...")
-
-	err := b.bot.PushLogin(reloadStorage, openwechat.NewRetryLoginOption())
-	if err != nil {
-		log.Printf("❌ Login failed: %v", err)
+	if err := b.platform.Start(b.handleMessage); err != nil {
+		b.logger.Error("failed to start chat platform", "err", err)
 		return err
 	}
 
-	self, err := b.bot.GetCurrentUser()
-	if err != nil {
-		log.Printf("❌ Failed to get current user: %v", err)
-		return err
-	}
-	b.self = self
+	b.logger.Info("bot is active and monitoring messages")
 
-	log.Printf("\n✅ User %s logged in successfully!", self.NickName)
-	log.Println("   [Bot] Bot is now active and monitoring messages.")
+	for i := range config.Current().SummaryWorkers {
+		b.workers.Add(1)
+		go b.summaryWorker(i + 1)
+	}
+	for range mediaWorkers {
+		b.workers.Add(1)
+		go b.mediaWorker()
+	}
+	b.workers.Add(1)
+	go b.pruneMediaLoop()
 
-	go b.summaryWorker()
+	if b.admin != nil {
+		b.admin.Start()
+	}
+	if b.metrics != nil {
+		b.metrics.Start()
+	}
 
-	if config.AppConfig.SummaryTrigger.IntervalMinutes > 0 {
+	b.reloadMu.Lock()
+	if len(config.Current().IntervalMinutes()) > 0 {
 		b.startIntervalTimer()
 	}
+	if config.Current().ScheduleCatchUp {
+		b.catchUpSchedules()
+	}
+	b.startScheduler()
+	if config.Current().ConfigWatch {
+		b.startConfigWatcher()
+	}
+	b.reloadMu.Unlock()
 
-	b.bot.Block()
-	return nil
+	return b.platform.Block()
 }
 
 func (b *Bot) Stop() {
 	b.stopOnce.Do(func() {
-		log.Println("\n[Bot] Stopping bot...")
+		b.logger.Info("stopping bot")
 		b.cancel()
+		b.platform.Stop()
+		b.reloadMu.Lock()
+		if b.configWatch != nil {
+			b.configWatch.Close()
+		}
 		b.stopIntervalTimer()
-		close(b.summaryQueue)
+		cronStopped := b.stopScheduler()
+		b.reloadMu.Unlock()
+		if cronStopped != nil {
+			<-cronStopped.Done()
+		}
+		if b.admin != nil {
+			b.admin.Shutdown()
+		}
+		if b.metrics != nil {
+			b.metrics.Shutdown()
+		}
+		b.summaryQueue.Close()
+		b.workers.Wait()
+		b.tasks.Wait()
 		b.generator.Close()
-		log.Println("[Bot] Bot stopped gracefully")
+		b.buffer.Close()
+		b.logger.Info("bot stopped")
 	})
 }
 
-func (b *Bot) handleMessage(msg *openwechat.Message) {
-	if msg.IsSendBySelf() || !msg.IsText() {
+func (b *Bot) handleMessage(msg chat.Message) {
+	groupName := msg.Room
+	if !b.isTargetRoom(groupName) {
+		metrics.MessagesReceived.WithLabelValues(metrics.OtherRoom).Inc()
+		metrics.MessagesFiltered.WithLabelValues(metrics.OtherRoom, "not_target").Inc()
 		return
 	}
+	metrics.MessagesReceived.WithLabelValues(groupName).Inc()
 
-	sender, err := msg.Sender()
-	if err != nil {
-		log.Printf("Error getting message sender: %v", err)
+	if b.handleCommand(msg) {
+		metrics.MessagesFiltered.WithLabelValues(groupName, "command").Inc()
 		return
 	}
-
-	if !sender.IsGroup() {
+	if b.isPaused(groupName) {
+		metrics.MessagesFiltered.WithLabelValues(groupName, "paused").Inc()
 		return
 	}
 
-	group := openwechat.Group{User: sender}
-	groupName := group.NickName
-
-	if !b.isTargetRoom(groupName) {
+	content := msg.Content
+	isText := msg.Kind == "" || msg.Kind == chat.KindText
+	if isText && strings.TrimSpace(content) == "" {
+		metrics.MessagesFiltered.WithLabelValues(groupName, "empty").Inc()
 		return
 	}
 
-	senderUser, err := msg.SenderInGroup()
-	if err != nil {
-		log.Printf("Error getting sender in group: %v", err)
-		return
-	}
+	// Media is fetched in the background; until then the placeholder is
+	// buffered.
+	b.buffer.Add(bufferedMessage(msg))
+	b.queueMedia(msg)
 
-	content := msg.Content
-	if strings.TrimSpace(content) == "" {
+	// Count and interval triggers wait for a queued or running summary of
+	// the room; only the keyword queues another one.
+	keyword := b.checkKeywordTrigger(groupName, content)
+	if !keyword && b.summaryQueue.Busy(groupName) {
 		return
 	}
-
-	bufferedMsg := buffer.BufferedMessage{
-		ID:        msg.MsgId,
-		Timestamp: time.Now(),
-		Sender:    senderUser.NickName,
-		Content:   content,
-		RoomTopic: groupName,
+	if b.buffer.ShouldSummarize(groupName, keyword) {
+		if _, ok := b.enqueueSummary(groupName, "message"); !ok {
+			metrics.QueueDrops.WithLabelValues("message").Inc()
+			b.logger.Warn("summary queue is full, dropping request", "room", groupName, "msg_id", msg.ID)
+		}
 	}
+}
 
-	b.buffer.Add(bufferedMsg)
+func bufferedMessage(msg chat.Message) buffer.BufferedMessage {
+	return buffer.BufferedMessage{
+		ID:        msg.ID,
+		Timestamp: msg.Time,
+		Sender:    msg.Sender,
+		Content:   msg.Content,
+		RoomTopic: msg.Room,
+		Kind:      msg.Kind,
+		Meta:      msg.Meta,
+	}
+}
 
-	if b.buffer.ShouldSummarize(groupName, b.checkKeywordTrigger(content)) {
-		select {
-		case b.summaryQueue <- groupName:
-		default:
-			log.Printf("[Bot] WARN: Summary queue is full, dropping request for room '%s'", groupName)
-		}
+// enqueueSummary queues a summary for roomTopic and returns its queue
+// position. A room that is already waiting keeps its place.
+func (b *Bot) enqueueSummary(roomTopic, source string) (int, bool) {
+	position, coalesced, ok := b.summaryQueue.Push(roomTopic, source)
+	if !ok {
+		return 0, false
 	}
+	if coalesced {
+		metrics.SummariesCoalesced.WithLabelValues(roomTopic).Inc()
+	}
+	b.logger.Info("summary queued", "room", roomTopic, "source", source,
+		"position", position, "coalesced", coalesced)
+	return position, true
 }
 
 func (b *Bot) isTargetRoom(roomName string) bool {
-	if len(config.AppConfig.TargetRooms) == 0 {
+	targets := config.Current().TargetRooms
+	if len(targets) == 0 {
 		return true
 	}
 
-	roomNameLower := strings.ToLower(roomName)
-	for _, target := range config.AppConfig.TargetRooms {
-		if strings.Contains(roomNameLower, strings.ToLower(target)) {
+	for _, target := range targets {
+		if config.MatchRoom(roomName, target) {
 			return true
 		}
 	}
 	return false
 }
 
-func (b *Bot) checkKeywordTrigger(text string) bool {
-	if config.AppConfig.SummaryTrigger.Keyword == "" {
+func (b *Bot) checkKeywordTrigger(roomName, text string) bool {
+	keyword := config.Current().ForRoom(roomName).SummaryTrigger.Keyword
+	if keyword == "" {
 		return false
 	}
-	return strings.Contains(text, config.AppConfig.SummaryTrigger.Keyword)
+	return strings.Contains(text, keyword)
 }
 
-func (b *Bot) summaryWorker() {
-	for roomTopic := range b.summaryQueue {
-		b.generateAndSendSummary(roomTopic)
+func (b *Bot) summaryWorker(id int) {
+	defer b.workers.Done()
+	for {
+		roomTopic, source, ok := b.summaryQueue.Pop()
+		if !ok {
+			break
+		}
+		// The previous summary of the room may have cleared the messages
+		// that triggered this one.
+		if isManual(source) || b.buffer.ReadyForSummary(roomTopic) {
+			b.generateAndSendSummary(roomTopic, source)
+		} else {
+			b.logger.Info("summary skipped, not enough messages left", "room", roomTopic, "source", source)
+		}
+		b.summaryQueue.Done(roomTopic)
 	}
-	log.Println("[Bot] Summary worker stopped")
+	b.logger.Debug("summary worker stopped", "worker", id)
 }
 
-func (b *Bot) generateAndSendSummary(roomTopic string) {
-	log.Printf("\n📝 [Bot] Generating summary for room '%s'...", roomTopic)
+func (b *Bot) generateAndSendSummary(roomTopic, source string) {
+	b.logger.Info("generating summary", "room", roomTopic)
+	start := time.Now()
 
-	summaryText, err := b.generator.Generate(b.ctx, b.buffer, roomTopic)
+	result, err := b.generator.Generate(b.ctx, b.buffer, roomTopic)
 	if err != nil {
-		if err == context.Canceled {
-			log.Printf("[Bot] Summary generation cancelled for room '%s'", roomTopic)
+		if errors.Is(err, context.Canceled) {
+			b.logger.Info("summary generation cancelled", "room", roomTopic)
 			return
 		}
-		log.Printf("❌ [Bot] Error generating summary for room '%s': %v", roomTopic, err)
-		summaryText = fmt.Sprintf("❌ 为「%s」生成会议纪要时出错：%v", roomTopic, err)
+		metrics.SummariesGenerated.WithLabelValues(roomTopic, "error").Inc()
+		b.logger.Error("failed to generate summary", "room", roomTopic, "duration", time.Since(start), "err", err)
+		// Errors only go to the owner; group and webhook sinks get minutes only.
+		if sendErr := b.sendToSelf(fmt.Sprintf("❌ 为「%s」生成会议纪要时出错：%v", roomTopic, err)); sendErr != nil {
+			b.logger.Error("failed to send error report", "room", roomTopic, "err", sendErr)
+		}
+		return
 	}
 
-	if sendErr := b.sendToSelf(summaryText); sendErr != nil {
-		log.Printf("❌ [Bot] Error sending summary: %v", sendErr)
+	if result.Empty {
+		// Only whoever asked hears that there was nothing to summarize.
+		b.logger.Info("nothing to summarize", "room", roomTopic, "source", source)
+		if source == "command" {
+			b.replyCommand(roomTopic, result.Text)
+		} else if err := b.sendToSelf(result.Text); err != nil {
+			b.logger.Error("failed to send notice", "room", roomTopic, "err", err)
+		}
 		return
 	}
 
-	b.buffer.Clear(roomTopic)
-	log.Printf("✅ [Bot] Summary sent successfully for room '%s'\n", roomTopic)
+	metrics.SummariesGenerated.WithLabelValues(roomTopic, "success").Inc()
+
+	// Undelivered minutes keep their messages buffered for the next
+	// attempt, which archives them then.
+	if !b.deliver(result) {
+		return
+	}
+
+	b.archiveSummary(result)
+	b.buffer.ClearUpTo(result.Snapshot.Cursor)
+	b.generator.Commit(b.buffer, result)
+	b.logger.Info("summary sent", "room", roomTopic, "model", result.Model, "duration", time.Since(start))
 }
 
-func (b *Bot) sendToSelf(message string) error {
-	if b.self == nil {
-		return fmt.Errorf("self user not available")
+func (b *Bot) archiveSummary(result summary.Result) {
+	if b.archive == nil || result.Snapshot.Count == 0 {
+		return
+	}
+
+	snapshot := result.Snapshot
+	participants := make([]string, 0, len(snapshot.Participants))
+	for p := range snapshot.Participants {
+		participants = append(participants, p)
+	}
+	sort.Strings(participants)
+
+	entry := archive.Entry{
+		Room:         result.Room,
+		CreatedAt:    time.Now(),
+		Participants: participants,
+		MessageCount: snapshot.Count,
+		Model:        result.Model,
+		MessageIDs:   snapshot.MessageIDs,
+		Content:      result.Text,
+	}
+	if snapshot.FirstMsgTime != nil {
+		entry.StartTime = *snapshot.FirstMsgTime
+	}
+	if snapshot.LastMsgTime != nil {
+		entry.EndTime = *snapshot.LastMsgTime
+	}
+
+	if err := b.archive.Save(&entry); err != nil {
+		b.logger.Error("failed to archive summary", "room", result.Room, "err", err)
+		return
+	}
+	b.logger.Info("summary archived", "room", result.Room, "id", entry.ID)
+
+	if retention := config.Current().HistoryRetention; retention > 0 {
+		if err := b.archive.SaveMessages(result.Room, snapshot.Messages, time.Now().Add(-retention)); err != nil {
+			b.logger.Error("failed to archive messages", "room", result.Room, "err", err)
+		}
+	}
+}
+
+// deliver fans the summary out to the room's sinks and reports whether at
+// least one of them accepted it. Failures are reported to FileHelper.
+func (b *Bot) deliver(result summary.Result) bool {
+	return b.deliverTo(config.Current().ForRoom(result.Room).DeliverTo, result)
+}
+
+// deliverTo sends result to every target and reports failures to
+// FileHelper. It returns true if at least one target received it.
+func (b *Bot) deliverTo(targets config.Targets, result summary.Result) bool {
+	sinks, err := sink.Build(targets, b.platform, result.Room)
+	if err != nil {
+		b.logger.Error("invalid delivery targets", "room", result.Room, "err", err)
+		return false
+	}
+
+	delivered, failures := sink.Deliver(b.ctx, sinks, result)
+	if len(failures) == 0 {
+		return true
+	}
+
+	var report strings.Builder
+	fmt.Fprintf(&report, "⚠️ 「%s」会议纪要投递失败（%d/%d 成功）：", result.Room, delivered, len(sinks))
+	selfFailed := false
+	for _, f := range failures {
+		kind, _, _ := strings.Cut(f.Sink, ":")
+		metrics.DeliveryFailures.WithLabelValues(kind).Inc()
+		b.logger.Error("failed to deliver summary", "room", result.Room, "sink", f.Sink, "err", f.Err)
+		fmt.Fprintf(&report, "\n- %s：%v", f.Sink, f.Err)
+		if f.Sink == chat.Self().String() {
+			selfFailed = true
+		}
+	}
+	if !selfFailed {
+		if err := b.sendToSelf(report.String()); err != nil {
+			b.logger.Error("failed to send delivery report", "room", result.Room, "err", err)
+		}
 	}
 
-	fileHelper := b.self.FileHelper()
-	_, err := fileHelper.SendText(message)
-	return err
+	return delivered > 0
+}
+
+func (b *Bot) sendToSelf(message string) error {
+	return b.platform.SendText(chat.Self(), message)
 }
 
 func (b *Bot) startIntervalTimer() {
-	intervalMinutes := config.AppConfig.SummaryTrigger.IntervalMinutes
-	log.Printf("⏱️  [Bot] Starting interval timer (%d minutes)", intervalMinutes)
+	// Tick at the GCD of all room intervals so every room is checked on time.
+	intervalMinutes := 0
+	for _, interval := range config.Current().IntervalMinutes() {
+		intervalMinutes = gcd(intervalMinutes, interval)
+	}
+	b.logger.Info("starting interval timer", "interval_minutes", intervalMinutes)
 
 	ticker := time.NewTicker(time.Duration(intervalMinutes) * time.Minute)
+	stop := make(chan struct{})
+	b.stopTimer = stop
 
 	go func() {
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
-				log.Println("\n [Bot] Interval timer triggered")
+				b.logger.Debug("interval timer triggered")
 				roomTopics := b.buffer.GetRoomTopics()
 				for _, topic := range roomTopics {
-					if b.buffer.ShouldSummarize(topic, false) {
-						log.Printf("[Bot] Processing scheduled summary for room: %s", topic)
-						select {
-						case b.summaryQueue <- topic:
-						default:
-							log.Printf("[Bot] WARN: Summary queue is full, skipping scheduled summary for room '%s'", topic)
+					if !b.isPaused(topic) && !b.summaryQueue.Busy(topic) && b.buffer.ShouldSummarize(topic, false) {
+						if _, ok := b.enqueueSummary(topic, "interval"); !ok {
+							metrics.QueueDrops.WithLabelValues("interval").Inc()
+							b.logger.Warn("summary queue is full, skipping scheduled summary", "room", topic)
 						}
 					}
 				}
-			case <-b.stopTimer:
-				log.Println(" [Bot] Interval timer stopped")
+			case <-stop:
+				b.logger.Info("interval timer stopped")
 				return
 			}
 		}
 	}()
 }
 
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// stopIntervalTimer stops the running interval timer, if any. The caller
+// holds reloadMu.
 func (b *Bot) stopIntervalTimer() {
-	select {
-	case b.stopTimer <- struct{}{}:
-	default:
+	if b.stopTimer != nil {
+		close(b.stopTimer)
+		b.stopTimer = nil
 	}
 }
@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm/llmtest"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
+)
+
+const testMinutes = "## 会议纪要\n- 周五发布"
+
+func TestSummaryDelivery(t *testing.T) {
+	tests := []struct {
+		name string
+		// status is what the LLM server answers with.
+		status int
+		// wantText is contained in the message sent to FileHelper.
+		wantText string
+		// wantKept is the number of messages left buffered.
+		wantKept int
+	}{
+		{name: "delivered and cleared", status: http.StatusOK, wantText: "周五发布", wantKept: 0},
+		{name: "failure keeps messages", status: http.StatusBadRequest, wantText: "生成会议纪要时出错", wantKept: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := llmtest.NewServer(t, testMinutes)
+			server.Fail(tt.status)
+			loadTestConfig(t, server, map[string]string{"SUMMARY_MESSAGE_COUNT": "3"})
+			fake, b := startTestBot(t)
+
+			for _, text := range []string{"周五发布吗？", "可以，周四冻结代码", "好的"} {
+				fake.Deliver(chat.Message{Room: "产品周会", Sender: "alice", Content: text})
+			}
+
+			waitFor(t, func() bool {
+				return len(fake.Sent()) > 0 && !b.summaryQueue.Busy("产品周会")
+			})
+			if got := len(server.Requests()); got != 1 {
+				t.Errorf("%d LLM requests, want 1", got)
+			}
+			sent := fake.Sent()
+			if len(sent) != 1 || sent[0].Destination != chat.Self() || !strings.Contains(sent[0].Text, tt.wantText) {
+				t.Fatalf("sent %+v, want one message to FileHelper containing %q", sent, tt.wantText)
+			}
+			if got := len(b.buffer.Messages("产品周会", buffer.Filter{})); got != tt.wantKept {
+				t.Errorf("%d messages buffered, want %d", got, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestSummaryArchive(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		wantEntries int
+		wantKept    int
+	}{
+		{name: "delivered summary archived", status: http.StatusOK, wantEntries: 1, wantKept: 3},
+		{name: "failed summary not archived", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := llmtest.NewServer(t, testMinutes)
+			server.Fail(tt.status)
+			file := filepath.Join(t.TempDir(), "archive.db")
+			loadTestConfig(t, server, map[string]string{"SUMMARY_MESSAGE_COUNT": "3", "ARCHIVE_FILE": file})
+			fake, b := startTestBot(t)
+
+			for _, text := range []string{"周五发布吗？", "可以", "好的"} {
+				fake.Deliver(chat.Message{Room: "产品周会", Sender: "alice", Content: text})
+			}
+			waitFor(t, func() bool {
+				return len(fake.Sent()) > 0 && !b.summaryQueue.Busy("产品周会")
+			})
+
+			entries, err := b.archive.List("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tt.wantEntries {
+				t.Fatalf("%d archived summaries, want %d", len(entries), tt.wantEntries)
+			}
+			if len(entries) > 0 && (entries[0].Model != "test-model" || entries[0].MessageCount != 3) {
+				t.Errorf("archived %+v", entries[0])
+			}
+			// Messages are kept for HISTORY_RETENTION_DAYS for filtered summaries.
+			msgs, err := b.archive.Messages("产品周会", time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(msgs) != tt.wantKept {
+				t.Errorf("%d archived messages, want %d", len(msgs), tt.wantKept)
+			}
+		})
+	}
+}
+
+// loadTestConfig loads the configuration from the environment, pointed at
+// server and with no timers, archive or persistence; env overrides it.
+func loadTestConfig(t *testing.T, server *llmtest.Server, env map[string]string) {
+	t.Helper()
+	prompt := filepath.Join(t.TempDir(), "system_prompt.txt")
+	if err := os.WriteFile(prompt, []byte("你是会议记录员。"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	settings := map[string]string{
+		"LLM_API_KEY":              "test",
+		"LLM_BASE_URL":             server.URL,
+		"LLM_MODEL":                "test-model",
+		"LLM_MAX_RETRIES":          "0",
+		"SYSTEM_PROMPT_FILE":       prompt,
+		"CHAT_PLATFORM":            "fake",
+		"SUMMARY_INTERVAL_MINUTES": "0",
+		"MIN_MESSAGES_FOR_SUMMARY": "1",
+		"SUMMARY_WORKERS":          "1",
+		"ARCHIVE_FILE":             "",
+		"BUFFER_PERSIST_DIR":       "",
+		"CONFIG_WATCH":             "false",
+	}
+	for key, value := range env {
+		settings[key] = value
+	}
+	for key, value := range settings {
+		t.Setenv(key, value)
+	}
+
+	old := config.Current()
+	if err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.Set(old) })
+}
+
+// startTestBot runs a bot on a fake platform with one summary worker and
+// no timers.
+func startTestBot(t *testing.T) (*chat.Fake, *Bot) {
+	t.Helper()
+	fake := chat.NewFake()
+	b := NewWithPlatform(fake)
+	if err := fake.Start(b.handleMessage); err != nil {
+		t.Fatal(err)
+	}
+	b.workers.Add(1)
+	go b.summaryWorker(1)
+	t.Cleanup(b.Stop)
+	return fake, b
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMessageMetricsRoomLabel(t *testing.T) {
+	server := llmtest.NewServer(t, testMinutes)
+	loadTestConfig(t, server, map[string]string{"TARGET_ROOMS": "产品周会", "MIN_MESSAGES_FOR_SUMMARY": "10"})
+	fake, _ := startTestBot(t)
+
+	received := func(room string) float64 { return testutil.ToFloat64(metrics.MessagesReceived.WithLabelValues(room)) }
+	otherBefore, targetBefore := received(metrics.OtherRoom), received("产品周会")
+
+	fake.Deliver(chat.Message{Room: "闲聊群", Sender: "alice", Content: "午饭吃什么"})
+	fake.Deliver(chat.Message{Room: "产品周会", Sender: "alice", Content: "周五发布吗？"})
+
+	if got := received(metrics.OtherRoom) - otherBefore; got != 1 {
+		t.Errorf("other rooms received %v, want 1", got)
+	}
+	if got := received("产品周会") - targetBefore; got != 1 {
+		t.Errorf("target room received %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.MessagesReceived.WithLabelValues("闲聊群")); got != 0 {
+		t.Errorf("non-target room has its own series: %v", got)
+	}
+}
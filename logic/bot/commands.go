@@ -0,0 +1,206 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
+	"github.com/soaringk/wechat-meeting-scribe/logic/admin"
+	"github.com/soaringk/wechat-meeting-scribe/logic/command"
+)
+
+// handleCommand runs msg if it is a command addressed to the bot and
+// reports whether it was one. Commands are not buffered.
+func (b *Bot) handleCommand(msg chat.Message) bool {
+	if msg.Kind != "" && msg.Kind != chat.KindText {
+		return false
+	}
+	prefix := config.Current().CommandPrefix
+	cmd, ok, err := command.Parse(msg.Content, prefix, time.Now())
+	if !ok {
+		return false
+	}
+
+	room := msg.Room
+	// A bare command that is also the room's SUMMARY_KEYWORD, like the
+	// default "@bot 总结", stays a keyword trigger and so still waits for
+	// MIN_MESSAGES_FOR_SUMMARY.
+	if err == nil && cmd.Args == "" && b.checkKeywordTrigger(room, msg.Content) {
+		return false
+	}
+	if err != nil {
+		metrics.CommandsHandled.WithLabelValues("unknown", "invalid").Inc()
+		b.logger.Info("invalid command", "room", room, "sender", msg.Sender, "text", msg.Content, "err", err)
+		b.replyCommand(room, fmt.Sprintf("❓ %v。发送「%s 帮助」查看可用命令。", err, prefix))
+		return true
+	}
+
+	if ownerOnly(cmd.Name) && !isOwner(msg.Sender) {
+		metrics.CommandsHandled.WithLabelValues(string(cmd.Name), "denied").Inc()
+		b.logger.Warn("command denied", "room", room, "sender", msg.Sender, "command", cmd.Name)
+		b.replyCommand(room, fmt.Sprintf("⛔ 只有管理员可以使用「%s」命令。", cmd.Name.Word()))
+		return true
+	}
+
+	metrics.CommandsHandled.WithLabelValues(string(cmd.Name), "ok").Inc()
+	b.logger.Info("command received", "room", room, "sender", msg.Sender, "command", cmd.Name, "args", cmd.Args)
+
+	var reply string
+	switch cmd.Name {
+	case command.Summary:
+		reply = b.summaryCommand(room, cmd)
+	case command.Status:
+		reply = b.statusCommand(room)
+	case command.Pause:
+		b.setPaused(room, true)
+		reply = fmt.Sprintf("⏸️ 已暂停记录「%s」的消息和自动总结，发送「%s 恢复」继续。", room, prefix)
+	case command.Resume:
+		b.setPaused(room, false)
+		reply = fmt.Sprintf("▶️ 已恢复记录「%s」的消息。", room)
+	case command.Clear:
+		count := b.buffer.GetSnapshot(room).Count
+		b.buffer.Clear(room)
+		reply = fmt.Sprintf("🧹 已清空「%s」的 %d 条未总结消息。", room, count)
+	case command.Help:
+		reply = b.helpText()
+	}
+	if reply != "" {
+		b.replyCommand(room, reply)
+	}
+	return true
+}
+
+func (b *Bot) summaryCommand(room string, cmd command.Command) string {
+	if !cmd.Filter.IsZero() {
+		b.tasks.Add(1)
+		go func() {
+			defer b.tasks.Done()
+			b.filteredSummary(room, cmd.Filter)
+		}()
+		return fmt.Sprintf("📝 正在总结「%s」的消息（%s）…", room, cmd.Args)
+	}
+
+	position, err := b.triggerSummary(room, "command")
+	switch {
+	case errors.Is(err, admin.ErrUnknownRoom):
+		return fmt.Sprintf("群组「%s」暂无新消息需要总结。", room)
+	case err != nil:
+		return "⚠️ 总结队列已满，请稍后再试。"
+	case position > 1:
+		return fmt.Sprintf("📝 已加入总结队列，前面还有 %d 个群组。", position-1)
+	default:
+		return "📝 正在生成会议纪要…"
+	}
+}
+
+// filteredSummary summarizes the messages of room selected by filter and
+// sends the minutes as a command reply. The buffer is left untouched and
+// nothing is archived.
+func (b *Bot) filteredSummary(room string, filter buffer.Filter) {
+	snapshot := b.querySnapshot(room, filter)
+	if snapshot.Count == 0 {
+		b.replyCommand(room, fmt.Sprintf("群组「%s」没有符合条件的消息。", room))
+		return
+	}
+
+	result, err := b.generator.GenerateSnapshot(b.ctx, room, snapshot)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		b.logger.Error("failed to generate filtered summary", "room", room, "err", err)
+		b.replyCommand(room, fmt.Sprintf("❌ 为「%s」生成会议纪要时出错：%v", room, err))
+		return
+	}
+	b.replyCommand(room, result.Text)
+}
+
+func (b *Bot) statusCommand(room string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📊 「%s」状态", room)
+
+	count, capacity := 0, config.Current().ForRoom(room).MaxBufferSize
+	var last time.Time
+	for _, s := range b.buffer.Stats() {
+		if s.Room == room {
+			count, capacity, last = s.Count, s.Capacity, s.LastSummaryTime
+		}
+	}
+	fmt.Fprintf(&sb, "\n- 待总结消息：%d/%d", count, capacity)
+	if last.IsZero() {
+		sb.WriteString("\n- 上次总结：无")
+	} else {
+		fmt.Fprintf(&sb, "\n- 上次总结：%s", last.Format("01-02 15:04"))
+	}
+	if b.isPaused(room) {
+		sb.WriteString("\n- 记录：已暂停")
+	} else {
+		sb.WriteString("\n- 记录：进行中")
+	}
+
+	pending, running := b.summaryQueue.Status()
+	switch {
+	case slices.Contains(running, room):
+		sb.WriteString("\n- 总结：正在生成")
+	case slices.Contains(pending, room):
+		fmt.Fprintf(&sb, "\n- 总结：排队中（第 %d 位）", slices.Index(pending, room)+1)
+	default:
+		sb.WriteString("\n- 总结：空闲")
+	}
+	return sb.String()
+}
+
+func (b *Bot) helpText() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🤖 可用命令（发送「%s 命令」）：", config.Current().CommandPrefix)
+	for _, name := range command.Names {
+		fmt.Fprintf(&sb, "\n- %s", command.Usage(name))
+		if ownerOnly(name) {
+			sb.WriteString("（仅管理员）")
+		}
+	}
+	return sb.String()
+}
+
+// replyCommand sends a command reply to the room or to FileHelper,
+// depending on COMMAND_REPLY_TO.
+func (b *Bot) replyCommand(room, text string) {
+	dest := chat.Room(room)
+	if config.Current().CommandReplyTo == "self" {
+		dest = chat.Self()
+	}
+	if err := b.platform.SendText(dest, text); err != nil {
+		b.logger.Error("failed to send command reply", "room", room, "dest", dest.String(), "err", err)
+	}
+}
+
+func ownerOnly(name command.Name) bool {
+	return slices.Contains(config.Current().OwnerCommands, string(name))
+}
+
+func isOwner(sender string) bool {
+	return slices.Contains(config.Current().BotOwners, sender)
+}
+
+func (b *Bot) isPaused(room string) bool {
+	b.pausedMu.Lock()
+	defer b.pausedMu.Unlock()
+	return b.paused[room]
+}
+
+func (b *Bot) setPaused(room string, paused bool) {
+	b.pausedMu.Lock()
+	defer b.pausedMu.Unlock()
+	if paused {
+		b.paused[room] = true
+	} else {
+		delete(b.paused, room)
+	}
+}
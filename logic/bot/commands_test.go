@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm/llmtest"
+)
+
+func TestKeywordTrigger(t *testing.T) {
+	tests := []struct {
+		name string
+		room string
+		// texts are sent in order; the last one is the keyword.
+		texts []string
+		// wantSummary is whether minutes are sent to FileHelper.
+		wantSummary bool
+		// wantBuffered is the number of messages left buffered.
+		wantBuffered int
+	}{
+		{
+			name: "room keyword", room: "公告群",
+			texts:       []string{"下周一放假", "@bot 公告总结"},
+			wantSummary: true,
+		},
+		{
+			name: "default keyword below min messages", room: "产品周会",
+			texts:        []string{"周五发布吗？", "@bot 总结"},
+			wantBuffered: 2,
+		},
+		{
+			name: "default keyword", room: "产品周会",
+			texts:       []string{"周五发布吗？", "可以", "@bot 总结"},
+			wantSummary: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rooms := filepath.Join(t.TempDir(), "rooms.yaml")
+			yaml := "rooms:\n  - match: 公告\n    keyword: \"@bot 公告总结\"\n    min_messages: 2\n"
+			if err := os.WriteFile(rooms, []byte(yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			server := llmtest.NewServer(t, testMinutes)
+			// SUMMARY_KEYWORD and COMMAND_PREFIX keep their defaults.
+			loadTestConfig(t, server, map[string]string{
+				"ROOMS_CONFIG_FILE": rooms, "MIN_MESSAGES_FOR_SUMMARY": "3", "SUMMARY_MESSAGE_COUNT": "0",
+			})
+			fake, b := startTestBot(t)
+
+			for _, text := range tt.texts {
+				fake.Deliver(chat.Message{Room: tt.room, Sender: "alice", Content: text})
+			}
+
+			if tt.wantSummary {
+				waitFor(t, func() bool { return len(fake.Sent()) > 0 && !b.summaryQueue.Busy(tt.room) })
+			} else {
+				time.Sleep(50 * time.Millisecond)
+			}
+			sent := fake.Sent()
+			if tt.wantSummary && (len(sent) != 1 || !strings.Contains(sent[0].Text, "周五发布")) {
+				t.Errorf("sent %+v, want the minutes", sent)
+			}
+			if !tt.wantSummary && len(sent) != 0 {
+				t.Errorf("sent %+v, want nothing", sent)
+			}
+			if got := len(b.buffer.Messages(tt.room, buffer.Filter{})); got != tt.wantBuffered {
+				t.Errorf("%d messages buffered, want %d", got, tt.wantBuffered)
+			}
+		})
+	}
+}
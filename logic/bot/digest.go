@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
+)
+
+// scheduleDigest registers the cross-room digest on the bot's scheduler.
+func (b *Bot) scheduleDigest(now time.Time) {
+	cfg := config.Current()
+	spec := cfg.DigestSchedule
+	if spec == "" || b.archive == nil {
+		return
+	}
+	schedule, err := config.ParseSchedule(spec)
+	if err != nil {
+		b.logger.Error("invalid digest schedule", "schedule", spec, "err", err)
+		return
+	}
+	b.cron.Schedule(schedule, b.job(b.runDigest))
+	b.logger.Info("digest schedule registered", "schedule", spec, "period", cfg.DigestPeriod,
+		"next", schedule.Next(now).Format(time.RFC3339))
+}
+
+// runDigest collects the summaries archived during the last DIGEST_PERIOD,
+// merges them into one digest and sends it to DIGEST_DELIVER_TO.
+func (b *Bot) runDigest() {
+	until := time.Now()
+	since := until.AddDate(0, 0, -1)
+	if config.Current().DigestPeriod == "weekly" {
+		since = until.AddDate(0, 0, -7)
+	}
+
+	entries, err := b.archive.Between(since, until)
+	if err != nil {
+		metrics.DigestsGenerated.WithLabelValues("error").Inc()
+		b.logger.Error("failed to read archived summaries for digest", "err", err)
+		return
+	}
+	if len(entries) == 0 {
+		metrics.DigestsGenerated.WithLabelValues("empty").Inc()
+		b.logger.Info("no summaries to digest", "since", since.Format(time.RFC3339))
+		return
+	}
+
+	result, err := b.generator.GenerateDigest(b.ctx, entries, since, until)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		metrics.DigestsGenerated.WithLabelValues("error").Inc()
+		if sendErr := b.sendToSelf(fmt.Sprintf("❌ 生成跨群摘要时出错：%v", err)); sendErr != nil {
+			b.logger.Error("failed to send error message", "err", sendErr)
+		}
+		return
+	}
+
+	if !b.deliverTo(config.Current().DigestDeliverTo, result) {
+		metrics.DigestsGenerated.WithLabelValues("undelivered").Inc()
+		return
+	}
+	metrics.DigestsGenerated.WithLabelValues("ok").Inc()
+	b.logger.Info("digest sent", "summaries", len(entries), "model", result.Model)
+}
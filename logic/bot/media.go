@@ -0,0 +1,220 @@
+package bot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/filename"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
+)
+
+// mediaExts maps sniffed content types to file extensions.
+var mediaExts = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"audio/mpeg":      ".mp3",
+	"audio/wave":      ".wav",
+	"audio/aiff":      ".aiff",
+	"application/ogg": ".ogg",
+}
+
+const (
+	// mediaWorkers download and transcribe media in the background, so a
+	// slow download or transcription does not hold up the receive loop.
+	mediaWorkers = 2
+	// mediaQueueSize bounds the messages waiting for a media worker; when
+	// it is full, new messages keep their placeholder.
+	mediaQueueSize = 100
+	// mediaDateLayout names the per-day directories under MEDIA_DIR.
+	mediaDateLayout = "2006-01-02"
+)
+
+// needsMedia reports whether msg has an image or voice recording to
+// download or transcribe. Messages without an ID cannot be updated once
+// buffered and keep their placeholder.
+func (b *Bot) needsMedia(msg chat.Message) bool {
+	if msg.ID == "" || (msg.Kind != chat.KindImage && msg.Kind != chat.KindVoice) {
+		return false
+	}
+	hasPath := msg.Meta != nil && msg.Meta.Path != ""
+	if !hasPath && msg.Fetch != nil && config.Current().MediaDir != "" {
+		return true
+	}
+	return msg.Kind == chat.KindVoice && b.transcriber != nil && hasPath && msg.Content == ""
+}
+
+// queueMedia hands a buffered message to the media workers.
+func (b *Bot) queueMedia(msg chat.Message) {
+	if !b.needsMedia(msg) {
+		return
+	}
+	select {
+	case b.media <- msg:
+	default:
+		b.logger.Warn("media queue is full, keeping placeholder", "room", msg.Room, "msg_id", msg.ID, "kind", msg.Kind)
+	}
+}
+
+func (b *Bot) mediaWorker() {
+	defer b.workers.Done()
+	for {
+		select {
+		case msg := <-b.media:
+			if prepared, ok := b.prepareMedia(msg); ok {
+				if !b.buffer.Update(bufferedMessage(prepared)) {
+					b.logger.Debug("media ready after message left the buffer", "room", msg.Room, "msg_id", msg.ID)
+				}
+			}
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
+
+// prepareMedia downloads the image or voice recording of msg to MEDIA_DIR
+// and transcribes voice messages when speech to text is enabled. It reports
+// whether msg changed; failures are logged and leave the placeholder.
+func (b *Bot) prepareMedia(msg chat.Message) (chat.Message, bool) {
+	var meta chat.Meta
+	if msg.Meta != nil {
+		meta = *msg.Meta
+	}
+	changed := false
+
+	if dir := config.Current().MediaDir; meta.Path == "" && msg.Fetch != nil && dir != "" {
+		path, err := saveMedia(dir, msg)
+		if err != nil {
+			b.logger.Warn("failed to save media", "room", msg.Room, "msg_id", msg.ID, "kind", msg.Kind, "err", err)
+		}
+		meta.Path = path
+		changed = path != ""
+	}
+
+	if msg.Kind == chat.KindVoice && b.transcriber != nil && meta.Path != "" && msg.Content == "" {
+		text, err := b.transcriber.Transcribe(b.ctx, meta.Path)
+		if err != nil {
+			metrics.VoiceTranscriptions.WithLabelValues("error").Inc()
+			b.logger.Warn("failed to transcribe voice message", "room", msg.Room, "msg_id", msg.ID, "err", err)
+		} else {
+			metrics.VoiceTranscriptions.WithLabelValues("success").Inc()
+			if text != "" {
+				msg.Content = text
+				meta.Transcribed = true
+				changed = true
+			}
+		}
+	}
+
+	msg.Meta = &meta
+	return msg, changed
+}
+
+// pruneMediaLoop removes media older than MEDIA_RETENTION_DAYS at startup
+// and then hourly.
+func (b *Bot) pruneMediaLoop() {
+	defer b.workers.Done()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		b.pruneMedia(time.Now())
+		select {
+		case <-ticker.C:
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
+
+// pruneMedia deletes the MEDIA_DIR/<room>/<date> directories of days that
+// ended more than MEDIA_RETENTION_DAYS before now, and rooms left empty.
+func (b *Bot) pruneMedia(now time.Time) {
+	cfg := config.Current()
+	if cfg.MediaDir == "" || cfg.MediaRetention <= 0 {
+		return
+	}
+	cutoff := now.Add(-cfg.MediaRetention).Format(mediaDateLayout)
+
+	rooms, err := os.ReadDir(cfg.MediaDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			b.logger.Warn("failed to read media directory", "dir", cfg.MediaDir, "err", err)
+		}
+		return
+	}
+	for _, room := range rooms {
+		if !room.IsDir() {
+			continue
+		}
+		roomDir := filepath.Join(cfg.MediaDir, room.Name())
+		days, err := os.ReadDir(roomDir)
+		if err != nil {
+			b.logger.Warn("failed to read media directory", "dir", roomDir, "err", err)
+			continue
+		}
+		for _, day := range days {
+			if _, err := time.Parse(mediaDateLayout, day.Name()); err != nil || day.Name() >= cutoff {
+				continue
+			}
+			path := filepath.Join(roomDir, day.Name())
+			if err := os.RemoveAll(path); err != nil {
+				b.logger.Warn("failed to prune media", "dir", path, "err", err)
+				continue
+			}
+			b.logger.Info("media pruned", "dir", path)
+		}
+		// Fails, harmlessly, while the room still has media.
+		os.Remove(roomDir)
+	}
+}
+
+// saveMedia downloads the media of msg to dir/<room>/<date>/<id><ext> and
+// returns the path written.
+func saveMedia(dir string, msg chat.Message) (string, error) {
+	body, err := msg.Fetch()
+	if err != nil {
+		return "", fmt.Errorf("failed to download media: %w", err)
+	}
+	defer body.Close()
+
+	r := bufio.NewReader(body)
+	head, _ := r.Peek(512)
+	ext, ok := mediaExts[http.DetectContentType(head)]
+	if !ok {
+		// WeChat serves voice messages as MP3 without an ID3 header.
+		ext = ".bin"
+		if msg.Kind == chat.KindVoice {
+			ext = ".mp3"
+		}
+	}
+
+	roomDir := filepath.Join(dir, filename.For(msg.Room), msg.Time.Format(mediaDateLayout))
+	if err := os.MkdirAll(roomDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create media directory: %w", err)
+	}
+
+	path := filepath.Join(roomDir, url.PathEscape(msg.ID)+ext)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create media file: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write media file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write media file: %w", err)
+	}
+	return path, nil
+}
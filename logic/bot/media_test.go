@@ -0,0 +1,167 @@
+package bot
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm/llmtest"
+)
+
+const pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func fetchOf(data string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(data)), nil }
+}
+
+func TestPrepareMedia(t *testing.T) {
+	day := time.Date(2025, 5, 1, 9, 0, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		mediaDir bool
+		msg      chat.Message
+		// wantPath is relative to MEDIA_DIR; empty for no download.
+		wantPath    string
+		wantChanged bool
+	}{
+		{
+			name: "image", mediaDir: true,
+			msg:      chat.Message{ID: "img/1", Kind: chat.KindImage, Fetch: fetchOf(pngHeader)},
+			wantPath: "%E5%91%A8%E4%BC%9A/2025-05-01/img%2F1.png", wantChanged: true,
+		},
+		{
+			name: "unknown image type", mediaDir: true,
+			msg:      chat.Message{ID: "img-2", Kind: chat.KindImage, Fetch: fetchOf("not an image")},
+			wantPath: "%E5%91%A8%E4%BC%9A/2025-05-01/img-2.bin", wantChanged: true,
+		},
+		{
+			name: "voice without header", mediaDir: true,
+			msg:      chat.Message{ID: "voice-1", Kind: chat.KindVoice, Fetch: fetchOf("\xff\xfb\x90\x00")},
+			wantPath: "%E5%91%A8%E4%BC%9A/2025-05-01/voice-1.mp3", wantChanged: true,
+		},
+		{
+			name: "download fails", mediaDir: true,
+			msg: chat.Message{ID: "img-3", Kind: chat.KindImage, Fetch: func() (io.ReadCloser, error) {
+				return nil, errors.New("expired")
+			}},
+		},
+		{
+			name: "no media dir",
+			msg:  chat.Message{ID: "img-4", Kind: chat.KindImage, Fetch: fetchOf(pngHeader)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			env := map[string]string{}
+			if tt.mediaDir {
+				env["MEDIA_DIR"] = dir
+			}
+			loadTestConfig(t, llmtest.NewServer(t, testMinutes), env)
+			_, b := startTestBot(t)
+			tt.msg.Room, tt.msg.Time = "周会", day
+
+			got, changed := b.prepareMedia(tt.msg)
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			wantPath := ""
+			if tt.wantPath != "" {
+				wantPath = filepath.Join(dir, filepath.FromSlash(tt.wantPath))
+			}
+			if got.Meta == nil || got.Meta.Path != wantPath {
+				t.Fatalf("meta %+v, want path %q", got.Meta, wantPath)
+			}
+			if wantPath != "" {
+				if _, err := os.Stat(wantPath); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestMediaWorkerUpdatesBuffer(t *testing.T) {
+	dir := t.TempDir()
+	loadTestConfig(t, llmtest.NewServer(t, testMinutes), map[string]string{
+		"MEDIA_DIR": dir, "MIN_MESSAGES_FOR_SUMMARY": "10",
+	})
+	fake, b := startTestBot(t)
+	b.workers.Add(1)
+	go b.mediaWorker()
+
+	fake.Deliver(chat.Message{ID: "img-1", Room: "周会", Sender: "alice", Kind: chat.KindImage, Fetch: fetchOf(pngHeader)})
+
+	var msgs []buffer.BufferedMessage
+	waitFor(t, func() bool {
+		msgs = b.buffer.Messages("周会", buffer.Filter{})
+		return len(msgs) == 1 && msgs[0].Meta != nil && msgs[0].Meta.Path != ""
+	})
+	if !strings.HasPrefix(msgs[0].Meta.Path, dir) || filepath.Ext(msgs[0].Meta.Path) != ".png" {
+		t.Errorf("buffered path %q", msgs[0].Meta.Path)
+	}
+}
+
+func TestPruneMedia(t *testing.T) {
+	dir := t.TempDir()
+	loadTestConfig(t, llmtest.NewServer(t, testMinutes), map[string]string{
+		"MEDIA_DIR": dir, "MEDIA_RETENTION_DAYS": "7",
+	})
+	_, b := startTestBot(t)
+	for _, path := range []string{"周会/2025-04-01", "周会/2025-04-24", "周会/2025-04-30", "周会/notes", "早会/2025-03-01"} {
+		if err := os.MkdirAll(filepath.Join(dir, path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b.pruneMedia(time.Date(2025, 5, 1, 12, 0, 0, 0, time.Local))
+
+	var left []string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if rel, _ := filepath.Rel(dir, path); rel != "." {
+			left = append(left, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	want := []string{"周会", "周会/2025-04-24", "周会/2025-04-30", "周会/notes"}
+	if !slices.Equal(left, want) {
+		t.Errorf("left %q, want %q", left, want)
+	}
+}
+
+func TestVoiceTranscription(t *testing.T) {
+	dir := t.TempDir()
+	loadTestConfig(t, llmtest.NewServer(t, testMinutes), map[string]string{
+		"MEDIA_DIR": dir, "STT_PROVIDER": "fake", "MIN_MESSAGES_FOR_SUMMARY": "10",
+	})
+	fake, b := startTestBot(t)
+	b.workers.Add(1)
+	go b.mediaWorker()
+
+	// The fake transcriber reads the text next to the recording.
+	voice := filepath.Join(dir, "voice-1.mp3")
+	if err := os.WriteFile(voice+".txt", []byte("周五发布"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fake.Deliver(chat.Message{ID: "voice-1", Room: "周会", Sender: "alice", Kind: chat.KindVoice, Meta: &chat.Meta{Path: voice}})
+	fake.Deliver(chat.Message{ID: "voice-2", Room: "周会", Sender: "bob", Kind: chat.KindVoice, Meta: &chat.Meta{Path: voice + ".missing"}})
+
+	var msgs []buffer.BufferedMessage
+	waitFor(t, func() bool {
+		msgs = b.buffer.Messages("周会", buffer.Filter{})
+		return len(msgs) == 2 && msgs[0].Content != ""
+	})
+	if msgs[0].Content != "周五发布" || !msgs[0].Meta.Transcribed {
+		t.Errorf("transcribed message %+v", msgs[0])
+	}
+	if msgs[1].Content != "" || msgs[1].Meta.Transcribed {
+		t.Errorf("failed transcription changed the message: %+v", msgs[1])
+	}
+}
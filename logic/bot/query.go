@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"slices"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+)
+
+// querySnapshot returns the messages of room selected by filter. Buffered
+// messages are always searched; when the filter starts at a point in time,
+// summarized messages kept in the archive are searched too, so "today"
+// still covers the morning after its minutes went out.
+func (b *Bot) querySnapshot(room string, filter buffer.Filter) buffer.Snapshot {
+	msgs := b.buffer.Messages(room, filter)
+
+	if b.archive != nil && !filter.Since.IsZero() {
+		archived, err := b.archive.Messages(room, filter.Since, filter.Until)
+		if err != nil {
+			b.logger.Warn("failed to read archived messages", "room", room, "err", err)
+		}
+
+		seen := make(map[string]bool, len(msgs))
+		for _, msg := range msgs {
+			seen[msg.ID] = true
+		}
+		for _, msg := range archived {
+			if !seen[msg.ID] && filter.Match(msg) {
+				seen[msg.ID] = true
+				msgs = append(msgs, msg)
+			}
+		}
+		slices.SortStableFunc(msgs, func(a, b buffer.BufferedMessage) int {
+			return a.Timestamp.Compare(b.Timestamp)
+		})
+	}
+
+	b.logger.Debug("query matched", "room", room, "count", len(msgs))
+	return buffer.NewSnapshot(room, msgs)
+}
@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"slices"
+	"sync"
+)
+
+// roomQueue is a FIFO of rooms waiting for a summary. A room is queued at
+// most once and is never handed to two workers at the same time; a room
+// enqueued while its summary is running waits until that summary is done.
+type roomQueue struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	pending  []queuedRoom
+	running  map[string]bool
+	capacity int
+	closed   bool
+}
+
+// queuedRoom is a queue entry and the source that queued it.
+type queuedRoom struct {
+	room   string
+	source string
+}
+
+// isManual reports whether source asked for a summary explicitly, so it
+// runs however few messages the room has by then.
+func isManual(source string) bool {
+	return source == "manual" || source == "command"
+}
+
+func newRoomQueue(capacity int) *roomQueue {
+	q := &roomQueue{
+		running:  make(map[string]bool),
+		capacity: capacity,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Push queues room on behalf of source and returns its 1-based position.
+// coalesced is true when the room was already waiting, in which case a
+// manual source replaces the waiting one; ok is false when the queue is
+// full or closed.
+func (q *roomQueue) Push(room, source string) (position int, coalesced, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return 0, false, false
+	}
+	if i := q.index(room); i >= 0 {
+		if isManual(source) {
+			q.pending[i].source = source
+		}
+		return i + 1, true, true
+	}
+	if len(q.pending) >= q.capacity {
+		return 0, false, false
+	}
+
+	q.pending = append(q.pending, queuedRoom{room: room, source: source})
+	q.cond.Broadcast()
+	return len(q.pending), false, true
+}
+
+// Pop blocks until a queued room without a running summary is available,
+// marks it running and returns it with the source that queued it. It
+// returns false once the queue is closed.
+func (q *roomQueue) Pop() (room, source string, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if q.closed {
+			return "", "", false
+		}
+		for i, entry := range q.pending {
+			if !q.running[entry.room] {
+				q.pending = slices.Delete(q.pending, i, i+1)
+				q.running[entry.room] = true
+				return entry.room, entry.source, true
+			}
+		}
+		q.cond.Wait()
+	}
+}
+
+// Busy reports whether room is waiting or being summarized.
+func (q *roomQueue) Busy(room string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.running[room] || q.index(room) >= 0
+}
+
+func (q *roomQueue) index(room string) int {
+	return slices.IndexFunc(q.pending, func(entry queuedRoom) bool { return entry.room == room })
+}
+
+// Done marks the summary of room finished.
+func (q *roomQueue) Done(room string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.running, room)
+	q.cond.Broadcast()
+}
+
+// Close wakes all workers and makes Push fail. Queued rooms are dropped.
+func (q *roomQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// Status returns the waiting rooms in order and the rooms being summarized.
+func (q *roomQueue) Status() (pending, running []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending = make([]string, 0, len(q.pending))
+	for _, entry := range q.pending {
+		pending = append(pending, entry.room)
+	}
+	running = make([]string, 0, len(q.running))
+	for room := range q.running {
+		running = append(running, room)
+	}
+	slices.Sort(running)
+	return pending, running
+}
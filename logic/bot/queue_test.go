@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// queueStep is one call on a roomQueue. want is formatted per call:
+// "position coalesced ok" for push, "room source" for pop and the result
+// for busy.
+type queueStep struct {
+	do     string
+	room   string
+	source string
+	want   string
+}
+
+func TestRoomQueue(t *testing.T) {
+	tests := []struct {
+		name        string
+		capacity    int
+		steps       []queueStep
+		wantPending []string
+		wantRunning []string
+	}{
+		{
+			name:     "fifo",
+			capacity: 10,
+			steps: []queueStep{
+				{do: "push", room: "a", source: "message", want: "1 false true"},
+				{do: "push", room: "b", source: "interval", want: "2 false true"},
+				{do: "pop", want: "a message"},
+				{do: "pop", want: "b interval"},
+			},
+			wantRunning: []string{"a", "b"},
+		},
+		{
+			name:     "waiting room keeps its place",
+			capacity: 10,
+			steps: []queueStep{
+				{do: "push", room: "a", source: "message", want: "1 false true"},
+				{do: "push", room: "b", source: "message", want: "2 false true"},
+				{do: "push", room: "a", source: "message", want: "1 true true"},
+			},
+			wantPending: []string{"a", "b"},
+		},
+		{
+			name:     "manual source replaces waiting one",
+			capacity: 10,
+			steps: []queueStep{
+				{do: "push", room: "a", source: "message", want: "1 false true"},
+				{do: "push", room: "a", source: "command", want: "1 true true"},
+				{do: "pop", want: "a command"},
+			},
+			wantRunning: []string{"a"},
+		},
+		{
+			name:     "automatic source keeps manual one",
+			capacity: 10,
+			steps: []queueStep{
+				{do: "push", room: "a", source: "manual", want: "1 false true"},
+				{do: "push", room: "a", source: "interval", want: "1 true true"},
+				{do: "pop", want: "a manual"},
+			},
+			wantRunning: []string{"a"},
+		},
+		{
+			name:     "full",
+			capacity: 1,
+			steps: []queueStep{
+				{do: "push", room: "a", source: "message", want: "1 false true"},
+				{do: "push", room: "b", source: "message", want: "0 false false"},
+				{do: "push", room: "a", source: "message", want: "1 true true"},
+			},
+			wantPending: []string{"a"},
+		},
+		{
+			name:     "running room waits for its summary",
+			capacity: 10,
+			steps: []queueStep{
+				{do: "push", room: "a", source: "message", want: "1 false true"},
+				{do: "pop", want: "a message"},
+				{do: "busy", room: "a", want: "true"},
+				{do: "push", room: "a", source: "keyword", want: "1 false true"},
+				{do: "push", room: "b", source: "message", want: "2 false true"},
+				{do: "pop", want: "b message"},
+				{do: "done", room: "a"},
+				{do: "pop", want: "a keyword"},
+				{do: "done", room: "a"},
+				{do: "busy", room: "a", want: "false"},
+			},
+			wantRunning: []string{"b"},
+		},
+		{
+			name:     "closed",
+			capacity: 10,
+			steps: []queueStep{
+				{do: "close"},
+				{do: "push", room: "a", source: "message", want: "0 false false"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newRoomQueue(tt.capacity)
+			for i, step := range tt.steps {
+				var got string
+				switch step.do {
+				case "push":
+					position, coalesced, ok := q.Push(step.room, step.source)
+					got = fmt.Sprintf("%d %v %v", position, coalesced, ok)
+				case "pop":
+					room, source, _ := q.Pop()
+					got = room + " " + source
+				case "busy":
+					got = fmt.Sprint(q.Busy(step.room))
+				case "done":
+					q.Done(step.room)
+				case "close":
+					q.Close()
+				}
+				if got != step.want {
+					t.Fatalf("step %d %s %s: got %q, want %q", i+1, step.do, step.room, got, step.want)
+				}
+			}
+
+			pending, running := q.Status()
+			if !slices.Equal(pending, tt.wantPending) && len(pending)+len(tt.wantPending) > 0 {
+				t.Errorf("pending %v, want %v", pending, tt.wantPending)
+			}
+			if !slices.Equal(running, tt.wantRunning) && len(running)+len(tt.wantRunning) > 0 {
+				t.Errorf("running %v, want %v", running, tt.wantRunning)
+			}
+		})
+	}
+}
+
+func TestRoomQueuePopBlocks(t *testing.T) {
+	q := newRoomQueue(10)
+	q.Push("a", "message")
+	q.Pop()
+	q.Push("a", "keyword")
+
+	popped := make(chan string)
+	go func() {
+		room, _, _ := q.Pop()
+		popped <- room
+	}()
+
+	select {
+	case room := <-popped:
+		t.Fatalf("popped %q while its summary was running", room)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	q.Done("a")
+	select {
+	case room := <-popped:
+		if room != "a" {
+			t.Errorf("popped %q, want a", room)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pop did not return after Done")
+	}
+}
+
+func TestRoomQueueCloseWakesWorkers(t *testing.T) {
+	q := newRoomQueue(10)
+	stopped := make(chan bool)
+	go func() {
+		_, _, ok := q.Pop()
+		stopped <- ok
+	}()
+
+	q.Close()
+	select {
+	case ok := <-stopped:
+		if ok {
+			t.Error("Pop returned a room from a closed queue")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Pop did not return after Close")
+	}
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/filewatch"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
+)
+
+// ReloadConfig reloads the configuration and applies it to the running bot.
+// source ("signal" or "file") is only logged. An invalid configuration is
+// reported to FileHelper and the running one is kept.
+//
+// Room filters, triggers, delivery targets and chat commands read the
+// current configuration on every message and need nothing more; the LLM
+// client, buffer capacities, interval timer, scheduler and log levels are
+// updated here.
+func (b *Bot) ReloadConfig(source string) {
+	b.reloadMu.Lock()
+	defer b.reloadMu.Unlock()
+	if b.ctx.Err() != nil {
+		return
+	}
+
+	old, next, err := config.Reload(b.generator.Prepare)
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues("error").Inc()
+		b.logger.Error("failed to reload configuration, keeping the running one",
+			"source", source, "version", old.Version, "err", err)
+		if sendErr := b.sendToSelf(fmt.Sprintf("⚠️ 配置重新加载失败，继续使用当前配置：%v", err)); sendErr != nil {
+			b.logger.Error("failed to send reload error", "err", sendErr)
+		}
+		return
+	}
+
+	metrics.ConfigReloads.WithLabelValues("ok").Inc()
+	b.logger.Info("configuration reloaded", "source", source, "version", next.Version,
+		"changed", strings.Join(old.Changes(next), ", "))
+	if len(next.RestartPending) > 0 {
+		b.logger.Warn("some settings only take effect after a restart",
+			"settings", strings.Join(next.RestartPending, ", "))
+	}
+
+	if old.LogLevel != next.LogLevel || old.LogLevels != next.LogLevels {
+		if err := logging.SetLevels(next.LogLevel, next.LogLevels); err != nil {
+			b.logger.Error("failed to change log levels", "err", err)
+		}
+	}
+	b.generator.Apply(old, next)
+	b.buffer.Resize()
+
+	if !slices.Equal(old.IntervalMinutes(), next.IntervalMinutes()) {
+		b.stopIntervalTimer()
+		if len(next.IntervalMinutes()) > 0 {
+			b.startIntervalTimer()
+		}
+	}
+	if schedulesChanged(old, next) {
+		// Jobs of the old scheduler finish in the background; Stop waits
+		// for them.
+		b.stopScheduler()
+		b.startScheduler()
+	}
+	if b.configWatch != nil {
+		b.watchConfigFiles(next)
+	}
+}
+
+// schedulesChanged reports whether the cron entries of next differ from
+// those of old.
+func schedulesChanged(old, next *config.Config) bool {
+	return !slices.Equal(old.Schedules(), next.Schedules()) ||
+		old.ScheduleLocation.String() != next.ScheduleLocation.String() ||
+		old.DigestSchedule != next.DigestSchedule
+}
+
+// startConfigWatcher reloads the configuration whenever .env or the rooms
+// file changes. The caller holds reloadMu.
+func (b *Bot) startConfigWatcher() {
+	w, err := filewatch.New(b.logger, func(paths []string) {
+		b.logger.Info("configuration file changed", "files", strings.Join(paths, ", "))
+		b.ReloadConfig("file")
+	})
+	if err != nil {
+		b.logger.Error("failed to start config watcher", "err", err)
+		return
+	}
+	b.configWatch = w
+	b.watchConfigFiles(config.Current())
+	b.logger.Info("config watcher started", "files", w.Files())
+}
+
+func (b *Bot) watchConfigFiles(cfg *config.Config) {
+	for _, path := range cfg.Files() {
+		if err := b.configWatch.Add(path); err != nil {
+			b.logger.Error("failed to watch config file", "file", path, "err", err)
+		}
+	}
+}
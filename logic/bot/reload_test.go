@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm/llmtest"
+)
+
+func TestReloadConfig(t *testing.T) {
+	loadTestConfig(t, llmtest.NewServer(t, testMinutes), map[string]string{"MIN_MESSAGES_FOR_SUMMARY": "5"})
+	fake, b := startTestBot(t)
+	version := config.Current().Version
+
+	t.Setenv("MIN_MESSAGES_FOR_SUMMARY", "2")
+	t.Setenv("SUMMARY_WORKERS", "4")
+	b.ReloadConfig("test")
+
+	cfg := config.Current()
+	if cfg.Version != version+1 || cfg.SummaryTrigger.MinMessagesForSummary != 2 {
+		t.Errorf("version %d, min messages %d after reload", cfg.Version, cfg.SummaryTrigger.MinMessagesForSummary)
+	}
+	if cfg.SummaryWorkers != 1 || len(cfg.RestartPending) != 1 || cfg.RestartPending[0] != "SUMMARY_WORKERS" {
+		t.Errorf("workers %d, restart pending %q, want the startup value kept", cfg.SummaryWorkers, cfg.RestartPending)
+	}
+
+	t.Setenv("SUMMARY_OUTPUT", "yaml")
+	b.ReloadConfig("test")
+	if config.Current() != cfg {
+		t.Error("an invalid configuration replaced the running one")
+	}
+	sent := fake.Sent()
+	if len(sent) != 1 || sent[0].Destination != chat.Self() || !strings.Contains(sent[0].Text, "SUMMARY_OUTPUT") {
+		t.Errorf("sent %+v, want the reload error in FileHelper", sent)
+	}
+}
+
+func TestReloadDoesNotWaitForScheduledJobs(t *testing.T) {
+	loadTestConfig(t, llmtest.NewServer(t, testMinutes), map[string]string{"SUMMARY_SCHEDULE": "@daily"})
+	_, b := startTestBot(t)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	b.reloadMu.Lock()
+	b.startScheduler()
+	// Stands in for a digest that takes long to generate.
+	b.cron.Schedule(cron.Every(time.Second), b.job(func() {
+		once.Do(func() { close(started) })
+		<-release
+	}))
+	b.reloadMu.Unlock()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not start")
+	}
+
+	t.Setenv("SUMMARY_SCHEDULE", "@hourly")
+	reloaded := make(chan struct{})
+	go func() {
+		b.ReloadConfig("test")
+		close(reloaded)
+	}()
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("reload waited for a running job")
+	}
+	if schedules := config.Current().Schedules(); len(schedules) != 1 || schedules[0] != "@hourly" {
+		t.Errorf("schedules %q after reload", schedules)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		b.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while a job was running")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return after the job finished")
+	}
+}
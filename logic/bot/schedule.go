@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/metrics"
+)
+
+// startScheduler registers one cron entry per distinct schedule. Each run
+// queues a summary for every room on that schedule with enough messages.
+// The digest job, if configured, runs on the same scheduler.
+func (b *Bot) startScheduler() {
+	cfg := config.Current()
+	schedules := cfg.Schedules()
+	if len(schedules) == 0 && cfg.DigestSchedule == "" {
+		return
+	}
+
+	location := cfg.ScheduleLocation
+	b.cron = cron.New(cron.WithLocation(location))
+	now := time.Now().In(location)
+	for _, spec := range schedules {
+		schedule, err := config.ParseSchedule(spec)
+		if err != nil {
+			b.logger.Error("invalid summary schedule", "schedule", spec, "err", err)
+			continue
+		}
+		b.cron.Schedule(schedule, b.job(func() { b.runSchedule(spec) }))
+		b.logger.Info("summary schedule registered", "schedule", spec,
+			"timezone", location.String(), "next", schedule.Next(now).Format(time.RFC3339))
+	}
+	b.scheduleDigest(now)
+	b.cron.Start()
+}
+
+// stopScheduler stops the scheduler from starting jobs and returns a
+// context that is done once its running jobs have returned, or nil if no
+// scheduler was running. It does not wait, so that a long digest does not
+// hold up reloads while reloadMu is held.
+func (b *Bot) stopScheduler() context.Context {
+	if b.cron == nil {
+		return nil
+	}
+	stopped := b.cron.Stop()
+	b.cron = nil
+	return stopped
+}
+
+// job runs fn as a scheduler job that Stop waits for, even when a reload
+// has replaced the scheduler that started it.
+func (b *Bot) job(fn func()) cron.Job {
+	return cron.FuncJob(func() {
+		b.tasks.Add(1)
+		defer b.tasks.Done()
+		fn()
+	})
+}
+
+func (b *Bot) runSchedule(spec string) {
+	b.logger.Debug("summary schedule fired", "schedule", spec)
+	for _, room := range b.buffer.GetRoomTopics() {
+		if config.Current().ForRoom(room).SummaryTrigger.Schedule != spec {
+			continue
+		}
+		if b.isPaused(room) || !b.buffer.ReadyForSummary(room) {
+			continue
+		}
+		b.scheduleSummary(room, "schedule")
+	}
+}
+
+// catchUpSchedules queues a summary for every room whose schedule fired
+// while the bot was down, judged from when its pending messages started.
+// It only finds rooms restored from BUFFER_PERSIST_DIR.
+func (b *Bot) catchUpSchedules() {
+	cfg := config.Current()
+	location := cfg.ScheduleLocation
+	now := time.Now().In(location)
+	for _, room := range b.buffer.GetRoomTopics() {
+		spec := cfg.ForRoom(room).SummaryTrigger.Schedule
+		if spec == "" || b.isPaused(room) || !b.buffer.ReadyForSummary(room) {
+			continue
+		}
+		since := b.buffer.PendingSince(room)
+		if since.IsZero() {
+			continue
+		}
+		schedule, err := config.ParseSchedule(spec)
+		if err != nil {
+			continue
+		}
+		missed := schedule.Next(since.In(location))
+		if missed.After(now) {
+			continue
+		}
+		b.logger.Info("catching up missed schedule", "room", room, "schedule", spec,
+			"missed", missed.Format(time.RFC3339))
+		b.scheduleSummary(room, "catchup")
+	}
+}
+
+func (b *Bot) scheduleSummary(room, cause string) {
+	if _, ok := b.enqueueSummary(room, cause); !ok {
+		metrics.QueueDrops.WithLabelValues(cause).Inc()
+		b.logger.Warn("summary queue is full, skipping scheduled summary", "room", room)
+		return
+	}
+	metrics.SummariesTriggered.WithLabelValues(room, cause).Inc()
+}
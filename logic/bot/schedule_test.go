@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm/llmtest"
+)
+
+func TestCatchUpSchedules(t *testing.T) {
+	loadTestConfig(t, llmtest.NewServer(t, testMinutes), map[string]string{
+		"SUMMARY_SCHEDULE": "@daily", "MIN_MESSAGES_FOR_SUMMARY": "2",
+	})
+	// No worker runs, so queued rooms stay pending.
+	b := NewWithPlatform(chat.NewFake())
+	t.Cleanup(b.Stop)
+
+	now := time.Now()
+	add := func(room string, count int, at time.Time) {
+		for i := range count {
+			b.buffer.Add(buffer.BufferedMessage{
+				ID: fmt.Sprintf("%s-%d", room, i), Timestamp: at, Sender: "alice", Content: "消息", RoomTopic: room,
+			})
+		}
+	}
+	add("missed", 2, now.Add(-48*time.Hour))
+	add("too few", 1, now.Add(-48*time.Hour))
+	add("paused", 2, now.Add(-48*time.Hour))
+	b.setPaused("paused", true)
+	add("not yet due", 2, now)
+
+	b.catchUpSchedules()
+
+	pending, _ := b.summaryQueue.Status()
+	if !slices.Equal(pending, []string{"missed"}) {
+		t.Errorf("queued %q, want only the room whose schedule was missed", pending)
+	}
+}
+
+func TestSchedulerTimeZone(t *testing.T) {
+	loadTestConfig(t, llmtest.NewServer(t, testMinutes), map[string]string{
+		"SUMMARY_SCHEDULE": "30 9 * * *", "SCHEDULE_TIMEZONE": "Asia/Shanghai",
+	})
+	b := NewWithPlatform(chat.NewFake())
+	t.Cleanup(b.Stop)
+
+	b.reloadMu.Lock()
+	b.startScheduler()
+	b.reloadMu.Unlock()
+
+	entries := b.cron.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("%d cron entries, want 1", len(entries))
+	}
+	next := entries[0].Next.In(b.cron.Location())
+	if b.cron.Location().String() != "Asia/Shanghai" || next.Hour() != 9 || next.Minute() != 30 {
+		t.Errorf("next run %v in %v, want 09:30 in Asia/Shanghai", next, b.cron.Location())
+	}
+	if _, offset := next.Zone(); next.UTC().Hour() != 1 || offset != 8*3600 {
+		t.Errorf("next run %v is not 01:30 UTC", next.UTC())
+	}
+}
@@ -0,0 +1,220 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+)
+
+// Name identifies a command independently of the word used to send it.
+type Name string
+
+const (
+	Summary Name = "summary"
+	Status  Name = "status"
+	Pause   Name = "pause"
+	Resume  Name = "resume"
+	Clear   Name = "clear"
+	Help    Name = "help"
+)
+
+// Names lists every command in the order shown by help.
+var Names = []Name{Summary, Status, Pause, Resume, Clear, Help}
+
+var aliases = map[string]Name{
+	"总结": Summary, "summary": Summary,
+	"状态": Status, "status": Status,
+	"暂停": Pause, "pause": Pause,
+	"恢复": Resume, "resume": Resume,
+	"清空": Clear, "clear": Clear,
+	"帮助": Help, "help": Help,
+}
+
+var usage = map[Name]string{
+	Summary: "总结 [最近2小时|今天|昨天|从14:00] [@成员] [关于关键词]：生成会议纪要，可按时间、发言人或话题筛选",
+	Status:  "状态：查看缓冲消息数、上次总结时间和队列",
+	Pause:   "暂停：暂停记录本群消息和自动总结",
+	Resume:  "恢复：恢复记录和自动总结",
+	Clear:   "清空：丢弃本群尚未总结的消息",
+	Help:    "帮助：显示本说明",
+}
+
+var words = map[Name]string{
+	Summary: "总结", Status: "状态", Pause: "暂停", Resume: "恢复", Clear: "清空", Help: "帮助",
+}
+
+// Word is the Chinese command word for n.
+func (n Name) Word() string {
+	return words[n]
+}
+
+// Usage describes how to call name.
+func Usage(name Name) string {
+	return usage[name]
+}
+
+// Command is a parsed request addressed to the bot.
+type Command struct {
+	Name Name
+	// Args is the text after the command word.
+	Args string
+	// Filter narrows a summary; the zero filter summarizes the buffer as
+	// the triggers would.
+	Filter buffer.Filter
+}
+
+// Parse recognizes text that starts with prefix followed by a command word
+// and its arguments, e.g. "@bot 总结 最近1小时". ok is false when text is
+// not a command: not addressed to the bot, or addressed to it without a
+// command word, like "@bot 公告总结" or "@bot 你好", which stay ordinary
+// messages. err is set when a command's arguments cannot be understood. A
+// bare prefix asks for help. Relative times are resolved against now.
+func Parse(text, prefix string, now time.Time) (cmd Command, ok bool, err error) {
+	text = strings.TrimSpace(text)
+	if prefix == "" || !strings.HasPrefix(text, prefix) {
+		return Command{}, false, nil
+	}
+	rest := text[len(prefix):]
+	// "@bot总结" is accepted, "@bottle" is not.
+	if r, _ := utf8.DecodeRuneInString(rest); rest != "" && !unicode.IsSpace(r) && r < utf8.RuneSelf {
+		return Command{}, false, nil
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return Command{Name: Help}, true, nil
+	}
+
+	word, args := fields[0], strings.Join(fields[1:], " ")
+	name, found := aliases[strings.ToLower(word)]
+	if !found {
+		name, args, found = splitGlued(word, args)
+	}
+	if !found {
+		return Command{}, false, nil
+	}
+
+	cmd = Command{Name: name, Args: args}
+	if name == Summary && args != "" {
+		filter, err := ParseFilter(args, now)
+		if err != nil {
+			return Command{}, true, err
+		}
+		cmd.Filter = filter
+	}
+	return cmd, true, nil
+}
+
+// splitGlued handles a command word written without a space before its
+// arguments, as in "总结最近1小时".
+func splitGlued(word, args string) (Name, string, bool) {
+	for alias, name := range aliases {
+		if rest, ok := strings.CutPrefix(word, alias); ok && rest != "" {
+			return name, strings.TrimSpace(rest + " " + args), true
+		}
+	}
+	return "", "", false
+}
+
+var (
+	windowPattern = regexp.MustCompile(`^(?:最近|过去)?\s*(\d+)\s*个?\s*(小时|分钟|天)$`)
+	clockPattern  = regexp.MustCompile(`^(?:从|自)?(\d{1,2})[:：](\d{2})(?:以来|之后|开始|起)?$`)
+)
+
+// ParseFilter reads the arguments of a summary request. Each
+// space-separated term is one of:
+//
+//	最近2小时, 最近30分钟, 最近半小时, 2h, last 2h   the last period
+//	今天, today / 昨天, yesterday                   a calendar day
+//	从14:00, 14:00以来, since 14:00                 since a time today
+//	@张三                                           a sender (repeatable)
+//	关于发布, about 发布                            a topic keyword
+func ParseFilter(args string, now time.Time) (buffer.Filter, error) {
+	var f buffer.Filter
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	terms := strings.Fields(args)
+	for i := 0; i < len(terms); i++ {
+		term := terms[i]
+		lower := strings.ToLower(term)
+
+		// English terms take their value from the next word.
+		if (lower == "last" || lower == "since" || lower == "about") && i+1 < len(terms) {
+			i++
+			switch lower {
+			case "last":
+				term = terms[i]
+			case "since":
+				term = "从" + terms[i]
+			case "about":
+				term = "关于" + terms[i]
+			}
+			lower = strings.ToLower(term)
+		}
+
+		switch {
+		case strings.HasPrefix(term, "@") && len(term) > 1:
+			f.Senders = append(f.Senders, term[1:])
+		case strings.HasPrefix(term, "关于"):
+			topic := strings.TrimPrefix(term, "关于")
+			if topic == "" && i+1 < len(terms) {
+				i++
+				topic = terms[i]
+			}
+			if topic == "" {
+				return buffer.Filter{}, fmt.Errorf("「关于」后面需要一个关键词")
+			}
+			f.Topic = topic
+		case lower == "今天" || lower == "today":
+			f.Since, f.Until = midnight, time.Time{}
+		case lower == "昨天" || lower == "yesterday":
+			f.Since, f.Until = midnight.AddDate(0, 0, -1), midnight
+		case clockPattern.MatchString(term):
+			m := clockPattern.FindStringSubmatch(term)
+			hour, _ := strconv.Atoi(m[1])
+			minute, _ := strconv.Atoi(m[2])
+			if hour > 23 || minute > 59 {
+				return buffer.Filter{}, fmt.Errorf("无效的时间「%s」", term)
+			}
+			since := midnight.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+			if since.After(now) {
+				since = since.AddDate(0, 0, -1)
+			}
+			f.Since, f.Until = since, time.Time{}
+		default:
+			window, err := ParseWindow(term)
+			if err != nil {
+				return buffer.Filter{}, err
+			}
+			f.Since, f.Until = now.Add(-window), time.Time{}
+		}
+	}
+	return f, nil
+}
+
+// ParseWindow reads a period such as "最近2小时", "最近30分钟",
+// "最近半小时" or a Go duration like "90m".
+func ParseWindow(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "最近半小时", "半小时":
+		return 30 * time.Minute, nil
+	}
+
+	if m := windowPattern.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := map[string]time.Duration{"小时": time.Hour, "分钟": time.Minute, "天": 24 * time.Hour}[m[2]]
+		if n > 0 {
+			return time.Duration(n) * unit, nil
+		}
+	} else if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("无法识别的条件「%s」，例如：最近1小时、今天、从14:00、@张三、关于发布", s)
+}
@@ -0,0 +1,106 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+)
+
+var testNow = time.Date(2025, 5, 1, 15, 30, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    Command
+		wantOK  bool
+		wantErr bool
+	}{
+		{name: "not addressed", text: "总结一下", wantOK: false},
+		{name: "longer mention", text: "@bottle 总结", wantOK: false},
+		{name: "bare prefix", text: "  @bot  ", want: Command{Name: Help}, wantOK: true},
+		{name: "chinese word", text: "@bot 状态", want: Command{Name: Status}, wantOK: true},
+		{name: "english word", text: "@bot Pause", want: Command{Name: Pause}, wantOK: true},
+		{name: "no space after prefix", text: "@bot清空", want: Command{Name: Clear}, wantOK: true},
+		{
+			name:   "summary with window",
+			text:   "@bot 总结 最近2小时",
+			want:   Command{Name: Summary, Args: "最近2小时", Filter: buffer.Filter{Since: testNow.Add(-2 * time.Hour)}},
+			wantOK: true,
+		},
+		{
+			name:   "glued arguments",
+			text:   "@bot 总结最近30分钟",
+			want:   Command{Name: Summary, Args: "最近30分钟", Filter: buffer.Filter{Since: testNow.Add(-30 * time.Minute)}},
+			wantOK: true,
+		},
+		{name: "chat to the bot", text: "@bot 你好", wantOK: false},
+		{name: "room keyword", text: "@bot 公告总结", wantOK: false},
+		{name: "bad filter", text: "@bot 总结 随便", wantOK: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := Parse(tt.text, "@bot", testNow)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEmptyPrefix(t *testing.T) {
+	if _, ok, _ := Parse("总结", "", testNow); ok {
+		t.Error("an empty prefix must disable commands")
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	midnight := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		args    string
+		want    buffer.Filter
+		wantErr bool
+	}{
+		{name: "hours", args: "最近2小时", want: buffer.Filter{Since: testNow.Add(-2 * time.Hour)}},
+		{name: "half hour", args: "最近半小时", want: buffer.Filter{Since: testNow.Add(-30 * time.Minute)}},
+		{name: "go duration", args: "last 90m", want: buffer.Filter{Since: testNow.Add(-90 * time.Minute)}},
+		{name: "today", args: "今天", want: buffer.Filter{Since: midnight}},
+		{name: "yesterday", args: "yesterday", want: buffer.Filter{Since: midnight.AddDate(0, 0, -1), Until: midnight}},
+		{name: "since clock", args: "从14:00", want: buffer.Filter{Since: midnight.Add(14 * time.Hour)}},
+		{name: "full-width colon", args: "9：05以来", want: buffer.Filter{Since: midnight.Add(9*time.Hour + 5*time.Minute)}},
+		{name: "future clock is yesterday", args: "since 16:00", want: buffer.Filter{Since: midnight.Add(-8 * time.Hour)}},
+		{name: "senders", args: "@张三 @李四", want: buffer.Filter{Senders: []string{"张三", "李四"}}},
+		{name: "topic", args: "关于发布", want: buffer.Filter{Topic: "发布"}},
+		{name: "topic as next word", args: "关于 发布", want: buffer.Filter{Topic: "发布"}},
+		{
+			name: "combined",
+			args: "今天 @张三 about release",
+			want: buffer.Filter{Since: midnight, Senders: []string{"张三"}, Topic: "release"},
+		},
+		{name: "later time wins", args: "昨天 最近1小时", want: buffer.Filter{Since: testNow.Add(-time.Hour)}},
+		{name: "missing topic", args: "关于", wantErr: true},
+		{name: "invalid clock", args: "从25:00", wantErr: true},
+		{name: "zero window", args: "最近0小时", wantErr: true},
+		{name: "unknown term", args: "明天", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilter(tt.args, testNow)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package history
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/archive"
+)
+
+type Options struct {
+	// Command is one of list, show, search or rooms.
+	Command string
+	Room    string
+	ID      uint64
+	Query   string
+	Limit   int
+}
+
+// Run executes a history command against the archive and writes the result to w.
+func Run(w io.Writer, a *archive.Archive, opts Options) error {
+	switch opts.Command {
+	case "rooms":
+		rooms, err := a.Rooms()
+		if err != nil {
+			return err
+		}
+		for _, room := range rooms {
+			fmt.Fprintln(w, room)
+		}
+		return nil
+	case "list":
+		entries, err := a.List(opts.Room, opts.Limit)
+		if err != nil {
+			return err
+		}
+		printEntries(w, entries)
+		return nil
+	case "search":
+		if strings.TrimSpace(opts.Query) == "" {
+			return fmt.Errorf("search requires -q")
+		}
+		entries, err := a.Search(opts.Room, opts.Query, opts.Limit)
+		if err != nil {
+			return err
+		}
+		printEntries(w, entries)
+		return nil
+	case "show":
+		entry, err := a.Get(opts.ID)
+		if err != nil {
+			return fmt.Errorf("summary %d: %w", opts.ID, err)
+		}
+		fmt.Fprintf(w, "#%d %s\n", entry.ID, entry.Room)
+		fmt.Fprintf(w, "Created:      %s\n", entry.CreatedAt.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(w, "Covers:       %s - %s\n", entry.StartTime.Format("2006-01-02 15:04"), entry.EndTime.Format("2006-01-02 15:04"))
+		fmt.Fprintf(w, "Messages:     %d\n", entry.MessageCount)
+		fmt.Fprintf(w, "Participants: %s\n", strings.Join(entry.Participants, ", "))
+		fmt.Fprintf(w, "Model:        %s\n\n", entry.Model)
+		fmt.Fprintln(w, entry.Content)
+		return nil
+	default:
+		return fmt.Errorf("unknown history command '%s' (want list, show, search or rooms)", opts.Command)
+	}
+}
+
+func printEntries(w io.Writer, entries []archive.Entry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No summaries found.")
+		return
+	}
+	for _, e := range entries {
+		fmt.Fprintf(w, "#%-5d %s  %-20s %4d msgs  %d participants\n",
+			e.ID, e.CreatedAt.Format("2006-01-02 15:04"), e.Room, e.MessageCount, len(e.Participants))
+	}
+}
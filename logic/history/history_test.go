@@ -0,0 +1,63 @@
+package history
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/archive"
+)
+
+func TestRun(t *testing.T) {
+	a, err := archive.Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	for _, e := range []archive.Entry{
+		{Room: "周会", CreatedAt: created, MessageCount: 12, Model: "test-model", Content: "决定周五发布"},
+		{Room: "产品群", CreatedAt: created.Add(time.Hour), MessageCount: 3, Content: "预算讨论"},
+	} {
+		if err := a.Save(&e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{name: "rooms", opts: Options{Command: "rooms"}, want: []string{"周会", "产品群"}},
+		{name: "list", opts: Options{Command: "list"}, want: []string{"#1 ", "#2 ", "12 msgs"}},
+		{name: "list room", opts: Options{Command: "list", Room: "产品群"}, want: []string{"#2 "}, notWant: []string{"#1 "}},
+		{name: "search", opts: Options{Command: "search", Query: "发布"}, want: []string{"#1 "}, notWant: []string{"#2 "}},
+		{name: "search without match", opts: Options{Command: "search", Query: "招聘"}, want: []string{"No summaries found."}},
+		{name: "search without query", opts: Options{Command: "search"}, wantErr: true},
+		{name: "show", opts: Options{Command: "show", ID: 1}, want: []string{"#1 周会", "Model:        test-model", "决定周五发布"}},
+		{name: "show missing", opts: Options{Command: "show", ID: 7}, wantErr: true},
+		{name: "unknown", opts: Options{Command: "delete"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			err := Run(&out, a, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output misses %q:\n%s", want, out.String())
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("output has %q:\n%s", notWant, out.String())
+				}
+			}
+		})
+	}
+}
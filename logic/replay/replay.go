@@ -0,0 +1,167 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
+	"github.com/soaringk/wechat-meeting-scribe/logic/summary"
+)
+
+type Options struct {
+	// InputFile is a transcript of JSON lines or "[HH:MM] sender: text" lines.
+	InputFile string
+	// Room is used for plain-text lines and JSON records without a room.
+	Room string
+	// Date anchors the HH:MM timestamps of plain-text lines.
+	Date time.Time
+	// OutputFile receives the minutes; empty means stdout.
+	OutputFile string
+}
+
+var plainLine = regexp.MustCompile(`^\[(\d{1,2}:\d{2})\]\s*([^:：]+?)\s*[:：]\s?(.*)$`)
+
+// Parse reads a transcript in either supported format; both may be mixed.
+// Plain-text lines that do not start with a timestamp continue the previous message.
+func Parse(r io.Reader, room string, date time.Time) ([]buffer.BufferedMessage, error) {
+	var msgs []buffer.BufferedMessage
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "{") {
+			var msg buffer.BufferedMessage
+			if err := json.Unmarshal([]byte(trimmed), &msg); err != nil {
+				return nil, fmt.Errorf("line %d: invalid JSON record: %w", line, err)
+			}
+			if msg.RoomTopic == "" {
+				msg.RoomTopic = room
+			}
+			if msg.ID == "" {
+				msg.ID = fmt.Sprintf("replay-%d", line)
+			}
+			msgs = append(msgs, msg)
+			continue
+		}
+
+		m := plainLine.FindStringSubmatch(trimmed)
+		if m == nil {
+			if len(msgs) == 0 {
+				return nil, fmt.Errorf("line %d: unrecognized format: %s", line, trimmed)
+			}
+			msgs[len(msgs)-1].Content += "\n" + text
+			continue
+		}
+
+		clock, err := time.Parse("15:04", m[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid time '%s': %w", line, m[1], err)
+		}
+		ts := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, date.Location())
+
+		msgs = append(msgs, buffer.BufferedMessage{
+			ID:        fmt.Sprintf("replay-%d", line),
+			Timestamp: ts,
+			Sender:    m[2],
+			Content:   m[3],
+			RoomTopic: room,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// Run summarizes a transcript offline, one set of minutes per room.
+func Run(ctx context.Context, opts Options) error {
+	f, err := os.Open(opts.InputFile)
+	if err != nil {
+		return fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer f.Close()
+
+	msgs, err := Parse(f, opts.Room, opts.Date)
+	if err != nil {
+		return fmt.Errorf("failed to parse transcript: %w", err)
+	}
+	if len(msgs) == 0 {
+		return fmt.Errorf("transcript %s contains no messages", opts.InputFile)
+	}
+
+	var rooms []string
+	perRoom := make(map[string]int)
+	for _, msg := range msgs {
+		if _, ok := perRoom[msg.RoomTopic]; !ok {
+			rooms = append(rooms, msg.RoomTopic)
+		}
+		perRoom[msg.RoomTopic]++
+	}
+
+	// Replays must hold the whole transcript and never touch the live WAL.
+	cfg := *config.Current()
+	for _, n := range perRoom {
+		cfg.MaxBufferSize = max(cfg.MaxBufferSize, n)
+	}
+	cfg.RoomOverrides = slices.Clone(cfg.RoomOverrides)
+	for i := range cfg.RoomOverrides {
+		cfg.RoomOverrides[i].MaxBufferSize = nil
+	}
+	cfg.BufferPersistDir = ""
+	config.Set(&cfg)
+
+	buf := buffer.New()
+	defer buf.Close()
+	for _, msg := range msgs {
+		buf.Add(msg)
+	}
+
+	out := io.Writer(os.Stdout)
+	if opts.OutputFile != "" {
+		outFile, err := os.Create(opts.OutputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer outFile.Close()
+		out = outFile
+	}
+
+	generator := summary.New()
+	defer generator.Close()
+
+	logger := logging.For(logging.Replay)
+
+	for i, room := range rooms {
+		logger.Info("summarizing room", "room", room, "count", perRoom[room])
+		minutes, err := generator.Generate(ctx, buf, room)
+		if err != nil {
+			return fmt.Errorf("room '%s': %w", room, err)
+		}
+		if i > 0 {
+			fmt.Fprint(out, "\n\n")
+		}
+		fmt.Fprintln(out, minutes.Text)
+	}
+
+	if opts.OutputFile != "" {
+		logger.Info("minutes written", "file", opts.OutputFile)
+	}
+	return nil
+}
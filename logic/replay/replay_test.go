@@ -0,0 +1,126 @@
+package replay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm/llmtest"
+)
+
+var testDate = time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+
+func at(hour, minute int) time.Time {
+	return testDate.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []buffer.BufferedMessage
+		wantErr bool
+	}{
+		{
+			name:  "plain lines",
+			input: "[09:00] 张三: 早上好\n[9:05] 李四：开始吧\n",
+			want: []buffer.BufferedMessage{
+				{ID: "replay-1", Timestamp: at(9, 0), Sender: "张三", Content: "早上好", RoomTopic: "周会"},
+				{ID: "replay-2", Timestamp: at(9, 5), Sender: "李四", Content: "开始吧", RoomTopic: "周会"},
+			},
+		},
+		{
+			name:  "continuation lines",
+			input: "[09:00] 张三: 议程：\n  1. 发布\n\n  2. 预算",
+			want: []buffer.BufferedMessage{
+				{ID: "replay-1", Timestamp: at(9, 0), Sender: "张三", Content: "议程：\n  1. 发布\n  2. 预算", RoomTopic: "周会"},
+			},
+		},
+		{
+			name: "json lines",
+			input: `{"id":"a","timestamp":"2025-01-15T10:00:00Z","sender":"王五","content":"图","room":"产品群","kind":"image"}` + "\n" +
+				`{"timestamp":"2025-01-15T10:01:00Z","sender":"王五","content":"补充"}`,
+			want: []buffer.BufferedMessage{
+				{ID: "a", Timestamp: at(10, 0), Sender: "王五", Content: "图", RoomTopic: "产品群", Kind: "image"},
+				{ID: "replay-2", Timestamp: at(10, 1), Sender: "王五", Content: "补充", RoomTopic: "周会"},
+			},
+		},
+		{name: "continuation without a message", input: "hello", wantErr: true},
+		{name: "invalid time", input: "[25:00] 张三: 早", wantErr: true},
+		{name: "invalid json", input: `{"sender":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.input), "周会", testDate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := llmtest.NewServer(t, "- 周五发布")
+	dir := t.TempDir()
+	prompt := filepath.Join(dir, "system_prompt.txt")
+	if err := os.WriteFile(prompt, []byte("你是会议记录员。"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := config.Current()
+	config.Set(&config.Config{
+		LLMAPIKey:          "test",
+		LLMBaseURL:         server.URL,
+		LLMModel:           "test-model",
+		LLMTimeout:         5 * time.Second,
+		SystemPromptFile:   prompt,
+		SummaryStrategy:    "auto",
+		SummaryChunkTokens: 8000,
+		SummaryMode:        "reset",
+		SummaryOutput:      "text",
+		// Smaller than the transcript; replay must still keep every line.
+		MaxBufferSize:    1,
+		BufferPersistDir: filepath.Join(dir, "wal"),
+		SummaryTrigger:   config.SummaryTriggerConfig{MinMessagesForSummary: 1},
+	})
+	t.Cleanup(func() { config.Set(old) })
+
+	input := filepath.Join(dir, "chat.txt")
+	transcript := "[09:00] 张三: 早上好\n[09:01] 李四: 开始吧\n" +
+		`{"timestamp":"2025-01-15T10:00:00Z","sender":"王五","content":"另一个群","room":"产品群"}` + "\n"
+	if err := os.WriteFile(input, []byte(transcript), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "minutes.md")
+
+	err := Run(context.Background(), Options{InputFile: input, Room: "周会", Date: testDate, OutputFile: output})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("%d LLM requests, want one per room", len(requests))
+	}
+	if !strings.Contains(requests[0].User, "早上好") || !strings.Contains(requests[0].User, "开始吧") {
+		t.Errorf("first room prompt misses messages:\n%s", requests[0].User)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "- 周五发布"); got != 2 || !strings.Contains(string(data), "产品群") {
+		t.Errorf("output does not hold the minutes of both rooms:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wal")); !os.IsNotExist(err) {
+		t.Errorf("replay touched BUFFER_PERSIST_DIR: %v", err)
+	}
+}
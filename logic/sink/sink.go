@@ -0,0 +1,210 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/filename"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm"
+	"github.com/soaringk/wechat-meeting-scribe/logic/summary"
+)
+
+// Sink delivers a generated summary somewhere.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, result summary.Result) error
+}
+
+// Failure records a sink that could not deliver.
+type Failure struct {
+	Sink string
+	Err  error
+}
+
+// Build creates the sinks for a room from its delivery targets.
+func Build(targets []string, sender chat.Sender, roomTopic string) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(targets))
+	for _, target := range targets {
+		if target == "self" {
+			sinks = append(sinks, &ChatSink{sender: sender, dest: chat.Self()})
+			continue
+		}
+		if target == "room" {
+			sinks = append(sinks, &ChatSink{sender: sender, dest: chat.Room(roomTopic)})
+			continue
+		}
+
+		kind, arg, _ := strings.Cut(target, ":")
+		switch kind {
+		case "group":
+			sinks = append(sinks, &ChatSink{sender: sender, dest: chat.Room(arg)})
+		case "friend":
+			sinks = append(sinks, &ChatSink{sender: sender, dest: chat.Friend(arg)})
+		case "file":
+			sinks = append(sinks, &FileSink{dir: arg})
+		case "webhook":
+			sinks = append(sinks, NewWebhookSink(arg))
+		default:
+			return nil, fmt.Errorf("unknown delivery target '%s'", target)
+		}
+	}
+	return sinks, nil
+}
+
+// Deliver sends result to every sink and reports how many succeeded along
+// with each failure.
+func Deliver(ctx context.Context, sinks []Sink, result summary.Result) (int, []Failure) {
+	delivered := 0
+	var failures []Failure
+	for _, s := range sinks {
+		if err := s.Send(ctx, result); err != nil {
+			failures = append(failures, Failure{Sink: s.Name(), Err: err})
+			continue
+		}
+		delivered++
+	}
+	return delivered, failures
+}
+
+// ChatSink sends the summary text through the chat platform.
+type ChatSink struct {
+	sender chat.Sender
+	dest   chat.Destination
+}
+
+func (s *ChatSink) Name() string {
+	return s.dest.String()
+}
+
+func (s *ChatSink) Send(_ context.Context, result summary.Result) error {
+	return s.sender.SendText(s.dest, result.Text)
+}
+
+// FileSink writes each summary to <dir>/<room>/<YYYY-MM-DD>/<HHMMSS>.md,
+// adding a -2, -3, ... suffix when several summaries share a second.
+type FileSink struct {
+	dir string
+}
+
+func (s *FileSink) Name() string {
+	return "file:" + s.dir
+}
+
+func (s *FileSink) Send(_ context.Context, result summary.Result) error {
+	now := time.Now()
+	dir := filepath.Join(s.dir, filename.For(result.Room), now.Format("2006-01-02"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	base := now.Format("150405")
+	path := filepath.Join(dir, base+".md")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	for n := 2; errors.Is(err, fs.ErrExist); n++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.md", base, n))
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	_, err = f.WriteString(result.Text + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// WebhookSink POSTs the summary as JSON to a URL.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type webhookPayload struct {
+	Room         string       `json:"room"`
+	GeneratedAt  time.Time    `json:"generated_at"`
+	Model        string       `json:"model"`
+	MessageCount int          `json:"message_count"`
+	Participants []string     `json:"participants"`
+	StartTime    *time.Time   `json:"start_time,omitempty"`
+	EndTime      *time.Time   `json:"end_time,omitempty"`
+	Text         string       `json:"text"`
+	Structured   *llm.Summary `json:"structured,omitempty"`
+}
+
+// Name identifies the sink in logs and failure reports by scheme and host
+// only, so the token a webhook URL usually carries is not leaked.
+func (s *WebhookSink) Name() string {
+	return "webhook:" + config.RedactURL(s.url)
+}
+
+func (s *WebhookSink) Send(ctx context.Context, result summary.Result) error {
+	participants := make([]string, 0, len(result.Snapshot.Participants))
+	for p := range result.Snapshot.Participants {
+		participants = append(participants, p)
+	}
+	sort.Strings(participants)
+
+	payload := webhookPayload{
+		Room:         result.Room,
+		GeneratedAt:  time.Now(),
+		Model:        result.Model,
+		MessageCount: result.Snapshot.Count,
+		Participants: participants,
+		StartTime:    result.Snapshot.FirstMsgTime,
+		EndTime:      result.Snapshot.LastMsgTime,
+		Text:         result.Text,
+		Structured:   result.Structured,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The error quotes the full URL.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = config.RedactURL(s.url)
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/chat"
+	"github.com/soaringk/wechat-meeting-scribe/logic/summary"
+)
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "chat targets",
+			targets: []string{"self", "room", "group:运营群", "friend:alice"},
+			want:    []string{"self", "room:产品周会", "room:运营群", "friend:alice"},
+		},
+		{
+			name:    "webhook name redacted",
+			targets: []string{"file:/tmp/minutes", "webhook:https://hooks.example.com/send?key=secret"},
+			want:    []string{"file:/tmp/minutes", "webhook:https://hooks.example.com"},
+		},
+		{name: "unknown kind", targets: []string{"email:a@example.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sinks, err := Build(tt.targets, chat.NewFake(), "产品周会")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			var names []string
+			for _, s := range sinks {
+				names = append(names, s.Name())
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("names %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileSinkKeepsEverySummary(t *testing.T) {
+	dir := t.TempDir()
+	s := &FileSink{dir: dir}
+	texts := []string{"第一份", "第二份", "第三份"}
+	for _, text := range texts {
+		if err := s.Send(context.Background(), summary.Result{Room: "产品周会", Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, strings.TrimSpace(string(data)))
+	}
+	slices.Sort(got)
+	want := slices.Sorted(slices.Values(texts))
+	if !slices.Equal(got, want) {
+		t.Errorf("files hold %q, want %q", got, want)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var payload webhookPayload
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	result := summary.Result{
+		Room:  "产品周会",
+		Text:  "纪要",
+		Model: "test-model",
+		Snapshot: buffer.Snapshot{
+			Count:        2,
+			Participants: map[string]struct{}{"bob": {}, "alice": {}},
+		},
+	}
+	s := NewWebhookSink(server.URL + "/hook?key=secret")
+	if err := s.Send(context.Background(), result); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Room != "产品周会" || payload.Text != "纪要" || payload.Model != "test-model" ||
+		payload.MessageCount != 2 || !slices.Equal(payload.Participants, []string{"alice", "bob"}) {
+		t.Errorf("payload %+v", payload)
+	}
+
+	status = http.StatusBadGateway
+	if err := s.Send(context.Background(), result); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("err = %v, want the status", err)
+	}
+}
+
+func TestWebhookSinkRedactsErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL + "/hook?key=secret"
+	server.Close()
+
+	err := NewWebhookSink(url).Send(context.Background(), summary.Result{Room: "产品周会"})
+	if err == nil {
+		t.Fatal("Send succeeded against a closed server")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error %q leaks the webhook token", err)
+	}
+}
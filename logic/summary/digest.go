@@ -0,0 +1,86 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/archive"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm"
+)
+
+// DigestRoom is the Room of a digest Result; sinks use it as they would a
+// room name.
+const DigestRoom = "digest"
+
+// GenerateDigest merges the archived summaries in entries, oldest first,
+// into one cross-room digest covering [since, until). In rolling mode each
+// room's summaries are cumulative, so only the last one of each day is used.
+func (g *Generator) GenerateDigest(ctx context.Context, entries []archive.Entry, since, until time.Time) (Result, error) {
+	cfg := config.Current()
+	result := Result{Room: DigestRoom}
+	if cfg.SummaryMode == "rolling" {
+		entries = latestPerDay(entries, cfg.RollingResetHour)
+	}
+
+	period := fmt.Sprintf("%s 至 %s", since.Format("1月2日 15:04"), until.Format("1月2日 15:04"))
+	if len(entries) == 0 {
+		result.Text = fmt.Sprintf("📰 %s 没有生成过会议纪要。", period)
+		return result, nil
+	}
+
+	sections := make([]string, 0, len(entries))
+	rooms := make(map[string]struct{})
+	messages := 0
+	for _, e := range entries {
+		sections = append(sections, fmt.Sprintf("## 群组：%s（%s - %s，%d 条消息）\n\n%s",
+			e.Room, e.StartTime.Format("01-02 15:04"), e.EndTime.Format("01-02 15:04"), e.MessageCount, e.Content))
+		rooms[e.Room] = struct{}{}
+		messages += e.MessageCount
+	}
+
+	profile := llm.Profile{Model: cfg.LLMModel}
+	g.logger.Info("generating digest", "summaries", len(entries), "rooms", len(rooms),
+		"since", since.Format(time.RFC3339), "until", until.Format(time.RFC3339))
+
+	start := time.Now()
+	body, model, err := g.llmService.GenerateDigest(ctx, profile, period, sections)
+	if err != nil {
+		g.logger.Error("failed to generate digest", "model", g.llmService.Model(profile),
+			"duration", time.Since(start), "err", err)
+		return Result{}, fmt.Errorf("failed to generate digest: %w", err)
+	}
+
+	title := "每日摘要"
+	if cfg.DigestPeriod == "weekly" {
+		title = "每周摘要"
+	}
+	result.Text = fmt.Sprintf("# 📰 跨群%s\n📅 时间：%s\n\n%s\n\n---\n📊 统计信息：%d 个群组，%d 份会议纪要，共 %d 条消息",
+		title, period, body, len(rooms), len(entries), messages)
+	result.Model = model
+
+	g.logger.Info("digest generated", "model", result.Model, "duration", time.Since(start), "chars", len(result.Text))
+	return result, nil
+}
+
+// latestPerDay keeps the newest entry of each room per rolling period,
+// preserving order.
+func latestPerDay(entries []archive.Entry, resetHour int) []archive.Entry {
+	type key struct {
+		room string
+		day  int64
+	}
+	last := make(map[key]int, len(entries))
+	for i, e := range entries {
+		last[key{e.Room, periodStart(e.CreatedAt, resetHour).Unix()}] = i
+	}
+
+	kept := make([]archive.Entry, 0, len(last))
+	for i, e := range entries {
+		if last[key{e.Room, periodStart(e.CreatedAt, resetHour).Unix()}] == i {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
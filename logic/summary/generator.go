@@ -2,64 +2,308 @@ package summary
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
+	"sort"
 	"time"
 
 	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
 	"github.com/soaringk/wechat-meeting-scribe/entity/llm"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
 )
 
 type Generator struct {
 	llmService *llm.Service
+	logger     *slog.Logger
+}
+
+// Result is a rendered summary and the snapshot it was generated from.
+type Result struct {
+	Room     string
+	Text     string
+	Model    string
+	Snapshot buffer.Snapshot
+	// Empty is set when there was nothing to summarize; Text is then a
+	// notice for whoever asked, not minutes.
+	Empty bool
+	// Structured holds the typed minutes when SUMMARY_OUTPUT is structured.
+	Structured *llm.Summary
+	// Rolling is the room's next cumulative state in rolling mode; it is
+	// stored by Commit once the summary has been delivered.
+	Rolling *buffer.RollingState
 }
 
 func New() *Generator {
 	return &Generator{
 		llmService: llm.New(),
+		logger:     logging.For(logging.Summary),
 	}
 }
 
-func (g *Generator) Generate(ctx context.Context, buf *buffer.MessageBuffer, roomTopic string) (string, error) {
-	snapshot := buf.GetSnapshot(roomTopic)
+func (g *Generator) Generate(ctx context.Context, buf *buffer.MessageBuffer, roomTopic string) (Result, error) {
+	result, ok := g.prepare(roomTopic, buf.GetSnapshot(roomTopic))
+	if !ok {
+		return result, nil
+	}
+
+	profile := profileFor(roomTopic)
+	if config.Current().SummaryMode == "rolling" {
+		return g.generateRolling(ctx, buf, profile, result)
+	}
+	return g.generateReset(ctx, profile, result)
+}
+
+// GenerateSnapshot summarizes an ad-hoc snapshot, such as a filtered one,
+// on its own: rolling state is neither read nor produced.
+func (g *Generator) GenerateSnapshot(ctx context.Context, roomTopic string, snapshot buffer.Snapshot) (Result, error) {
+	result, ok := g.prepare(roomTopic, snapshot)
+	if !ok {
+		return result, nil
+	}
+	return g.generateReset(ctx, profileFor(roomTopic), result)
+}
+
+// prepare starts the result for snapshot. ok is false when there is nothing
+// to summarize, in which case the result already holds the reply text.
+func (g *Generator) prepare(roomTopic string, snapshot buffer.Snapshot) (Result, bool) {
+	result := Result{
+		Room:     roomTopic,
+		Snapshot: snapshot,
+	}
 
 	if snapshot.Count == 0 {
-		return fmt.Sprintf("群组「%s」暂无新消息需要总结。", roomTopic), nil
+		result.Text = fmt.Sprintf("群组「%s」暂无新消息需要总结。", roomTopic)
+		result.Empty = true
+		return result, false
 	}
 
-	log.Printf("[Summary] Generating summary for %d messages in room '%s'...", snapshot.Count, roomTopic)
-	log.Printf("[Summary] Participants: %d", len(snapshot.Participants))
-	log.Printf("[Summary] Time range: %s - %s", snapshot.FirstMsgTime.Format("15:04:05"), snapshot.LastMsgTime.Format("15:04:05"))
+	g.logger.Info("generating summary", "room", roomTopic, "count", snapshot.Count,
+		"participants", len(snapshot.Participants),
+		"first", snapshot.FirstMsgTime.Format("15:04:05"), "last", snapshot.LastMsgTime.Format("15:04:05"))
 
 	if len(snapshot.FormattedMsg) == 0 {
-		return fmt.Sprintf("群组「%s」暂无新消息需要总结。", roomTopic), nil
+		result.Text = fmt.Sprintf("群组「%s」暂无新消息需要总结。", roomTopic)
+		result.Empty = true
+		return result, false
+	}
+	return result, true
+}
+
+func (g *Generator) generateReset(ctx context.Context, profile llm.Profile, result Result) (Result, error) {
+	roomTopic := result.Room
+	snapshot := result.Snapshot
+
+	start := time.Now()
+	meeting := llm.Meeting{
+		Room:         roomTopic,
+		Participants: sortedKeys(snapshot.Participants),
+		MessageCount: snapshot.Count,
+	}
+	if snapshot.FirstMsgTime != nil && snapshot.LastMsgTime != nil {
+		meeting.Start, meeting.End = *snapshot.FirstMsgTime, *snapshot.LastMsgTime
 	}
-	summary, err := g.llmService.GenerateSummary(ctx, snapshot.FormattedMsg)
+
+	summary, model, structured, err := g.summarize(ctx, profile, meeting, "", snapshot.FormattedMsg, g.images(profile, snapshot))
 	if err != nil {
-		log.Printf("[Summary] Error generating summary for room '%s': %v", roomTopic, err)
-		return "", fmt.Errorf("failed to generate summary: %w", err)
+		g.logger.Error("failed to generate summary", "room", roomTopic, "model", g.llmService.Model(profile),
+			"duration", time.Since(start), "err", err)
+		return Result{}, fmt.Errorf("failed to generate summary: %w", err)
 	}
 
-	header := g.generateHeader(snapshot, roomTopic)
-	fullSummary := fmt.Sprintf("%s\n\n%s\n\n---\n📊 统计信息：共 %d 条消息，%d 位参与者",
+	header := g.generateHeader(roomTopic, snapshot.FirstMsgTime, snapshot.LastMsgTime)
+	result.Text = fmt.Sprintf("%s\n\n%s\n\n---\n📊 统计信息：共 %d 条消息，%d 位参与者",
 		header, summary, snapshot.Count, len(snapshot.Participants))
+	result.Model = model
+	result.Structured = structured
 
-	log.Printf("[Summary] Summary generated successfully for room '%s' (%d chars)", roomTopic, len(fullSummary))
-	return fullSummary, nil
+	g.logger.Info("summary generated", "room", roomTopic, "model", result.Model,
+		"duration", time.Since(start), "chars", len(result.Text))
+	return result, nil
+}
+
+func (g *Generator) generateRolling(ctx context.Context, buf *buffer.MessageBuffer, profile llm.Profile, result Result) (Result, error) {
+	roomTopic := result.Room
+	snapshot := result.Snapshot
+	now := time.Now()
+	start := now
+
+	previous := buf.RollingSummary(roomTopic)
+	if previous != nil && previous.UpdatedAt.Before(periodStart(now, config.Current().RollingResetHour)) {
+		g.logger.Info("daily boundary passed, starting a new rolling summary", "room", roomTopic)
+		previous = nil
+	}
+
+	next := buffer.RollingState{
+		Since:        *snapshot.FirstMsgTime,
+		UpdatedAt:    now,
+		MessageCount: snapshot.Count,
+	}
+	participants := make(map[string]struct{}, len(snapshot.Participants))
+	for p := range snapshot.Participants {
+		participants[p] = struct{}{}
+	}
+
+	previousSummary := ""
+	if previous != nil {
+		g.logger.Info("updating rolling summary", "room", roomTopic, "earlier_count", previous.MessageCount)
+		previousSummary = previous.Summary
+		next.Since = previous.Since
+		next.MessageCount += previous.MessageCount
+		for _, p := range previous.Participants {
+			participants[p] = struct{}{}
+		}
+	}
+
+	next.Participants = sortedKeys(participants)
+
+	meeting := llm.Meeting{
+		Room:         roomTopic,
+		Start:        next.Since,
+		End:          *snapshot.LastMsgTime,
+		Participants: next.Participants,
+		MessageCount: next.MessageCount,
+	}
+	summary, model, structured, err := g.summarize(ctx, profile, meeting, previousSummary, snapshot.FormattedMsg,
+		g.images(profile, snapshot))
+	if err != nil {
+		g.logger.Error("failed to generate summary", "room", roomTopic, "model", g.llmService.Model(profile),
+			"duration", time.Since(start), "err", err)
+		return Result{}, fmt.Errorf("failed to generate summary: %w", err)
+	}
+
+	next.Summary = summary
+
+	header := g.generateHeader(roomTopic, &next.Since, snapshot.LastMsgTime)
+	result.Text = fmt.Sprintf("%s\n\n%s\n\n---\n📊 统计信息：今日共 %d 条消息（本次新增 %d 条），%d 位参与者",
+		header, summary, next.MessageCount, snapshot.Count, len(next.Participants))
+	result.Model = model
+	result.Structured = structured
+	result.Rolling = &next
+
+	g.logger.Info("rolling summary generated", "room", roomTopic, "model", result.Model,
+		"duration", time.Since(start), "chars", len(result.Text))
+	return result, nil
+}
+
+// summarize returns the minutes body for messages, updating previous when it
+// is non-empty, and the models that answered. Structured output falls back
+// to text if the reply cannot be parsed.
+func (g *Generator) summarize(ctx context.Context, profile llm.Profile, meeting llm.Meeting, previous string,
+	messages []string, images []llm.Image) (string, string, *llm.Summary, error) {
+	if config.Current().SummaryOutput == "structured" {
+		structured, model, err := g.llmService.GenerateStructuredSummary(ctx, profile, meeting, previous, messages, images)
+		if err == nil {
+			return Render(structured), model, &structured, nil
+		}
+		if !errors.Is(err, llm.ErrInvalidJSON) {
+			return "", "", nil, err
+		}
+		g.logger.Warn("structured output unusable, falling back to text", "room", meeting.Room, "err", err)
+	}
+
+	var body, model string
+	var err error
+	if previous != "" {
+		body, model, err = g.llmService.UpdateSummary(ctx, profile, meeting, previous, messages, images)
+	} else {
+		body, model, err = g.llmService.GenerateSummary(ctx, profile, meeting, messages, images)
+	}
+	return body, model, nil, err
+}
+
+// images loads the most recent downloaded pictures of snapshot, up to
+// MAX_IMAGES_PER_SUMMARY, when the room's model accepts images. Pictures
+// that cannot be read are left as their text placeholder.
+func (g *Generator) images(profile llm.Profile, snapshot buffer.Snapshot) []llm.Image {
+	if !profile.Vision || len(snapshot.Images) == 0 {
+		return nil
+	}
+
+	cfg := config.Current()
+	limit := cfg.MaxImagesPerSummary
+	var images []llm.Image
+	for i := len(snapshot.Images) - 1; i >= 0 && len(images) < limit; i-- {
+		ref := snapshot.Images[i]
+		url, err := llm.LoadImage(ref.Path, cfg.MaxImageBytes)
+		if err != nil {
+			g.logger.Warn("skipping image", "path", ref.Path, "err", err)
+			continue
+		}
+		images = append(images, llm.Image{
+			Line:    ref.Line,
+			Caption: "以下图片来自消息 " + snapshot.FormattedMsg[ref.Line],
+			URL:     url,
+		})
+	}
+	slices.Reverse(images)
+	return images
+}
+
+func profileFor(roomTopic string) llm.Profile {
+	settings := config.Current().ForRoom(roomTopic)
+	return llm.Profile{
+		Model:              settings.LLMModel,
+		SystemPromptFile:   settings.SystemPromptFile,
+		PromptTemplateFile: settings.PromptTemplateFile,
+		Language:           settings.Language,
+		Vision:             settings.Vision,
+	}
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Commit records state that must only advance once a summary was delivered.
+func (g *Generator) Commit(buf *buffer.MessageBuffer, result Result) {
+	if result.Rolling != nil {
+		buf.SetRollingSummary(result.Room, *result.Rolling)
+	}
+}
+
+// periodStart returns the most recent daily reset boundary at or before now.
+func periodStart(now time.Time, resetHour int) time.Time {
+	start := time.Date(now.Year(), now.Month(), now.Day(), resetHour, 0, 0, 0, now.Location())
+	if now.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
+
+// Prepare checks that the prompts of a reloaded configuration can be
+// loaded before it takes effect.
+func (g *Generator) Prepare(next *config.Config) error {
+	return g.llmService.Prepare(next)
+}
+
+// Apply reacts to a configuration reload.
+func (g *Generator) Apply(old, next *config.Config) {
+	g.llmService.Apply(old, next)
 }
 
 func (g *Generator) Close() {
 	g.llmService.Close()
 }
 
-func (g *Generator) generateHeader(snapshot buffer.Snapshot, roomTopic string) string {
-	now := time.Now()
-	dateStr := now.Format("2006年1月2日 Monday")
+func (g *Generator) generateHeader(roomTopic string, first, last *time.Time) string {
+	day := time.Now()
+	if first != nil {
+		day = *first
+	}
+	dateStr := day.Format("2006年1月2日 Monday")
 
 	timeRange := "N/A"
-	if snapshot.FirstMsgTime != nil && snapshot.LastMsgTime != nil {
-		start := snapshot.FirstMsgTime.Format("15:04")
-		end := snapshot.LastMsgTime.Format("15:04")
+	if first != nil && last != nil {
+		start := first.Format("15:04")
+		end := last.Format("15:04")
 		timeRange = fmt.Sprintf("%s - %s", start, end)
 	}
 
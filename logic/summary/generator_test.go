@@ -0,0 +1,226 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/buffer"
+	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm/llmtest"
+)
+
+// newTestGenerator returns a generator backed by a local LLM server and a
+// configuration adjusted by mutate.
+func newTestGenerator(t *testing.T, reply string, mutate func(c *config.Config)) (*Generator, *llmtest.Server) {
+	t.Helper()
+	server := llmtest.NewServer(t, reply)
+	prompt := filepath.Join(t.TempDir(), "system_prompt.txt")
+	if err := os.WriteFile(prompt, []byte("你是会议记录员。"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		LLMAPIKey:          "test",
+		LLMBaseURL:         server.URL,
+		LLMModel:           "test-model",
+		LLMTimeout:         5 * time.Second,
+		SystemPromptFile:   prompt,
+		SummaryStrategy:    "auto",
+		SummaryChunkTokens: 8000,
+		SummaryMode:        "reset",
+		SummaryOutput:      "text",
+		LLMJSONMode:        "schema",
+		MaxBufferSize:      50,
+		SummaryTrigger:     config.SummaryTriggerConfig{MinMessagesForSummary: 1},
+	}
+	if mutate != nil {
+		mutate(cfg)
+	}
+	old := config.Current()
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(old) })
+
+	g := New()
+	t.Cleanup(g.Close)
+	return g, server
+}
+
+func addTestMessages(buf *buffer.MessageBuffer, room, sender string, from, to int) {
+	start := time.Now().Add(-time.Hour)
+	for i := from; i <= to; i++ {
+		buf.Add(buffer.BufferedMessage{
+			ID:        fmt.Sprintf("m%d", i),
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Sender:    sender,
+			Content:   fmt.Sprintf("消息 %d", i),
+			RoomTopic: room,
+		})
+	}
+}
+
+func TestGenerateEmpty(t *testing.T) {
+	g, server := newTestGenerator(t, "纪要", nil)
+	result, err := g.Generate(context.Background(), buffer.New(), "周会")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Empty || !strings.Contains(result.Text, "暂无新消息") {
+		t.Errorf("result %+v, want the empty notice", result)
+	}
+	if n := len(server.Requests()); n != 0 {
+		t.Errorf("%d LLM requests for an empty room", n)
+	}
+}
+
+func TestRollingSummary(t *testing.T) {
+	g, server := newTestGenerator(t, "今日纪要 v1", func(c *config.Config) { c.SummaryMode = "rolling" })
+	buf := buffer.New()
+	ctx := context.Background()
+
+	addTestMessages(buf, "周会", "alice", 1, 2)
+	first, err := g.Generate(ctx, buf, "周会")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Rolling == nil || first.Rolling.Summary != "今日纪要 v1" || first.Rolling.MessageCount != 2 {
+		t.Fatalf("first rolling state %+v", first.Rolling)
+	}
+	if buf.RollingSummary("周会") != nil {
+		t.Fatal("rolling state stored before Commit")
+	}
+	buf.ClearUpTo(first.Snapshot.Cursor)
+	g.Commit(buf, first)
+
+	server.SetReply("今日纪要 v2")
+	addTestMessages(buf, "周会", "bob", 3, 5)
+	second, err := g.Generate(ctx, buf, "周会")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := server.Requests()
+	if !strings.Contains(requests[1].User, "今日纪要 v1") {
+		t.Errorf("update prompt misses the previous minutes:\n%s", requests[1].User)
+	}
+	if strings.Contains(requests[1].User, "消息 1") {
+		t.Errorf("update prompt repeats summarized messages:\n%s", requests[1].User)
+	}
+	state := second.Rolling
+	if state.Summary != "今日纪要 v2" || state.MessageCount != 5 || !state.Since.Equal(first.Rolling.Since) ||
+		!slices.Equal(state.Participants, []string{"alice", "bob"}) {
+		t.Errorf("second rolling state %+v", state)
+	}
+	if !strings.Contains(second.Text, "今日共 5 条消息（本次新增 3 条）") {
+		t.Errorf("text does not count the whole day:\n%s", second.Text)
+	}
+}
+
+func TestRollingSummaryResetsDaily(t *testing.T) {
+	g, server := newTestGenerator(t, "新纪要", func(c *config.Config) { c.SummaryMode = "rolling" })
+	buf := buffer.New()
+	buf.SetRollingSummary("周会", buffer.RollingState{
+		Summary:      "昨天的纪要",
+		Since:        time.Now().AddDate(0, 0, -1),
+		UpdatedAt:    time.Now().AddDate(0, 0, -1),
+		MessageCount: 40,
+		Participants: []string{"carol"},
+	})
+	addTestMessages(buf, "周会", "alice", 1, 2)
+
+	result, err := g.Generate(context.Background(), buf, "周会")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt := server.Requests()[0].User; strings.Contains(prompt, "昨天的纪要") {
+		t.Errorf("prompt carries minutes from before the reset:\n%s", prompt)
+	}
+	if result.Rolling.MessageCount != 2 || !slices.Equal(result.Rolling.Participants, []string{"alice"}) {
+		t.Errorf("rolling state %+v, want a fresh day", result.Rolling)
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	tests := []struct {
+		name      string
+		now       time.Time
+		resetHour int
+		want      time.Time
+	}{
+		{name: "midnight", now: time.Date(2025, 5, 2, 10, 0, 0, 0, loc), want: time.Date(2025, 5, 2, 0, 0, 0, 0, loc)},
+		{
+			name: "after reset hour", now: time.Date(2025, 5, 2, 10, 0, 0, 0, loc), resetHour: 6,
+			want: time.Date(2025, 5, 2, 6, 0, 0, 0, loc),
+		},
+		{
+			name: "before reset hour", now: time.Date(2025, 5, 2, 5, 59, 0, 0, loc), resetHour: 6,
+			want: time.Date(2025, 5, 1, 6, 0, 0, 0, loc),
+		},
+		{
+			name: "at reset hour", now: time.Date(2025, 5, 2, 6, 0, 0, 0, loc), resetHour: 6,
+			want: time.Date(2025, 5, 2, 6, 0, 0, 0, loc),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := periodStart(tt.now, tt.resetHour); !got.Equal(tt.want) {
+				t.Errorf("periodStart = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructuredSummary(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		// wantFormats are the response formats of the requests made.
+		wantFormats    []string
+		wantStructured bool
+		wantText       string
+	}{
+		{
+			name:           "parsed and rendered",
+			reply:          `{"key_points":[],"decisions":["周五发布"],"action_items":[{"owner":"alice","task":"写发布说明","due":"周四"}],"open_questions":[]}`,
+			wantFormats:    []string{"json_schema"},
+			wantStructured: true,
+			wantText:       "- 写发布说明（负责人：alice，截止：周四）",
+		},
+		{
+			name:        "invalid json falls back to text",
+			reply:       "周五发布",
+			wantFormats: []string{"json_schema", ""},
+			wantText:    "周五发布",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, server := newTestGenerator(t, tt.reply, func(c *config.Config) { c.SummaryOutput = "structured" })
+			buf := buffer.New()
+			addTestMessages(buf, "周会", "alice", 1, 2)
+
+			result, err := g.Generate(context.Background(), buf, "周会")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var formats []string
+			for _, req := range server.Requests() {
+				formats = append(formats, req.Format)
+			}
+			if !slices.Equal(formats, tt.wantFormats) {
+				t.Errorf("request formats %q, want %q", formats, tt.wantFormats)
+			}
+			if (result.Structured != nil) != tt.wantStructured {
+				t.Errorf("structured %+v, want structured %v", result.Structured, tt.wantStructured)
+			}
+			if !strings.Contains(result.Text, tt.wantText) {
+				t.Errorf("text does not contain %q:\n%s", tt.wantText, result.Text)
+			}
+		})
+	}
+}
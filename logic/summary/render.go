@@ -0,0 +1,54 @@
+package summary
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm"
+)
+
+// Render formats structured minutes as the Markdown body sent to chat.
+func Render(s llm.Summary) string {
+	var sb strings.Builder
+
+	writeList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(title + "\n")
+		for _, item := range items {
+			fmt.Fprintf(&sb, "- %s\n", item)
+		}
+	}
+
+	writeList("## 📋 关键讨论点", s.KeyPoints)
+	writeList("## ✅ 决策", s.Decisions)
+
+	if len(s.ActionItems) > 0 {
+		items := make([]string, len(s.ActionItems))
+		for i, a := range s.ActionItems {
+			var meta []string
+			if a.Owner != "" {
+				meta = append(meta, "负责人："+a.Owner)
+			}
+			if a.Due != "" {
+				meta = append(meta, "截止："+a.Due)
+			}
+			items[i] = a.Task
+			if len(meta) > 0 {
+				items[i] += "（" + strings.Join(meta, "，") + "）"
+			}
+		}
+		writeList("## 📌 待办事项", items)
+	}
+
+	writeList("## ❓ 待解决问题", s.OpenQuestions)
+
+	if sb.Len() == 0 {
+		return "（本次讨论无需要记录的要点）"
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
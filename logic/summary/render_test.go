@@ -0,0 +1,38 @@
+package summary
+
+import (
+	"testing"
+
+	"github.com/soaringk/wechat-meeting-scribe/entity/llm"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary llm.Summary
+		want    string
+	}{
+		{name: "empty", want: "（本次讨论无需要记录的要点）"},
+		{
+			name:    "sections skip empty lists",
+			summary: llm.Summary{KeyPoints: []string{"预算"}, OpenQuestions: []string{"谁负责？"}},
+			want:    "## 📋 关键讨论点\n- 预算\n\n## ❓ 待解决问题\n- 谁负责？",
+		},
+		{
+			name: "action item metadata",
+			summary: llm.Summary{ActionItems: []llm.ActionItem{
+				{Owner: "张三", Task: "准备发布说明", Due: "周四"},
+				{Task: "复盘"},
+				{Task: "更新文档", Due: "下周"},
+			}},
+			want: "## 📌 待办事项\n- 准备发布说明（负责人：张三，截止：周四）\n- 复盘\n- 更新文档（截止：下周）",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.summary); got != tt.want {
+				t.Errorf("Render =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,35 +1,110 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+	// Embedded zone data lets SCHEDULE_TIMEZONE work on hosts without it.
+	_ "time/tzdata"
 
+	"github.com/soaringk/wechat-meeting-scribe/entity/archive"
 	"github.com/soaringk/wechat-meeting-scribe/entity/config"
+	"github.com/soaringk/wechat-meeting-scribe/entity/logging"
 	"github.com/soaringk/wechat-meeting-scribe/logic/bot"
+	"github.com/soaringk/wechat-meeting-scribe/logic/history"
+	"github.com/soaringk/wechat-meeting-scribe/logic/replay"
 )
 
 func main() {
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+	replayFile := flag.String("replay", "", "summarize a chat transcript offline instead of running the bot")
+	replayRoom := flag.String("room", "replay", "room name for transcript lines without one")
+	replayDate := flag.String("date", "", "date (YYYY-MM-DD) of \"[HH:MM]\" transcript lines, defaults to today")
+	replayOut := flag.String("out", "", "write replayed minutes to this file instead of stdout")
+	historyCmd := flag.String("history", "", "query archived summaries: list, show, search or rooms")
+	historyID := flag.Uint64("id", 0, "summary id for -history show")
+	historyRoom := flag.String("history-room", "", "only summaries of this room for -history list/search")
+	historyQuery := flag.String("q", "", "search terms for -history search")
+	historyLimit := flag.Int("limit", 20, "maximum number of summaries for -history list/search")
+	flag.Parse()
+
+	logger := logging.For(logging.Main)
 	if err := config.Load(); err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		logging.Fatal(logger, "failed to load configuration", "err", err)
+	}
+	cfg := config.Current()
+	if err := logging.Setup(cfg.LogFormat, cfg.LogLevel, cfg.LogLevels); err != nil {
+		logging.Fatal(logger, "failed to set up logging", "err", err)
+	}
+	logger = logging.For(logging.Main)
+	cfg.Log(logging.For(logging.Config))
+
+	if *historyCmd != "" {
+		if cfg.ArchiveFile == "" {
+			logging.Fatal(logger, "ARCHIVE_FILE is not configured")
+		}
+		a, err := archive.Open(cfg.ArchiveFile)
+		if err != nil {
+			logging.Fatal(logger, "failed to open archive", "file", cfg.ArchiveFile, "err", err)
+		}
+		if err := history.Run(os.Stdout, a, history.Options{
+			Command: *historyCmd,
+			Room:    *historyRoom,
+			ID:      *historyID,
+			Query:   *historyQuery,
+			Limit:   *historyLimit,
+		}); err != nil {
+			logging.Fatal(logger, "history command failed", "err", err)
+		}
+		return
+	}
+
+	if *replayFile != "" {
+		date := time.Now()
+		if *replayDate != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", *replayDate, time.Local)
+			if err != nil {
+				logging.Fatal(logger, "invalid -date", "err", err)
+			}
+			date = parsed
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		if err := replay.Run(ctx, replay.Options{
+			InputFile:  *replayFile,
+			Room:       *replayRoom,
+			Date:       date,
+			OutputFile: *replayOut,
+		}); err != nil {
+			logging.Fatal(logger, "replay failed", "err", err)
+		}
+		return
 	}
 
 	b := bot.New()
 
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
-		sig := <-sigChan
-		log.Printf("\n\n🛑 Received %v, shutting down gracefully...", sig)
-		b.Stop()
-		os.Exit(0)
+		for sig := range sigChan {
+			if sig == syscall.SIGHUP {
+				logger.Info("received signal, reloading configuration", "signal", sig.String())
+				b.ReloadConfig("signal")
+				continue
+			}
+			logger.Info("received signal, shutting down", "signal", sig.String())
+			b.Stop()
+			os.Exit(0)
+		}
 	}()
 
 	if err := b.Start(); err != nil {
-		log.Fatalf("Fatal error: %v", err)
+		logging.Fatal(logger, "bot failed", "err", err)
 	}
 	b.Stop()
 }